@@ -0,0 +1,83 @@
+package transmissionrpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+)
+
+/*
+	Authentication
+	https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#41-authentication
+*/
+
+// Authenticator adds credentials to the HTTP requests sent to the daemon.
+// A 401 answer is reported as a HTTPStatusCode error matching ErrUnauthorized.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc allows to use a function as an Authenticator.
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate implements the Authenticator interface.
+func (af AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return af(req)
+}
+
+// BasicAuth returns an Authenticator using the HTTP basic authentication, as expected by the
+// daemon when rpc-authentication-required is enabled.
+func BasicAuth(username, password string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// BearerToken returns an Authenticator setting a static bearer token, for daemons behind an
+// authenticating reverse proxy.
+func BearerToken(token string) Authenticator {
+	return HeaderAuth("Authorization", "Bearer "+token)
+}
+
+// HeaderAuth returns an Authenticator setting a custom header (API key of a reverse proxy, etc...).
+func HeaderAuth(key, value string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set(key, value)
+		return nil
+	})
+}
+
+/*
+	TLS
+*/
+
+// TLSConfig contains the TLS settings of the default HTTP client.
+type TLSConfig struct {
+	// RootCAs are the certificate authorities used to verify the server certificate.
+	// The system pool is used if nil.
+	RootCAs *x509.CertPool
+	// Certificates are the client certificates presented to the server (mTLS reverse proxies).
+	Certificates []tls.Certificate
+	// ServerName overrides the name used to verify the server certificate (defaults to the endpoint host).
+	ServerName string
+	// InsecureSkipVerify disables the server certificate verification. Do not use it outside of tests.
+	InsecureSkipVerify bool
+}
+
+func (tc *TLSConfig) build() (config *tls.Config, err error) {
+	for index, certificate := range tc.Certificates {
+		if len(certificate.Certificate) == 0 {
+			return nil, fmt.Errorf("client certificate #%d is empty", index)
+		}
+	}
+	config = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		RootCAs:            tc.RootCAs,
+		Certificates:       tc.Certificates,
+		ServerName:         tc.ServerName,
+		InsecureSkipVerify: tc.InsecureSkipVerify, //nolint:gosec // explicit user choice
+	}
+	return
+}
@@ -0,0 +1,133 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+// newGuardedDaemon exposes daemon behind a proxy only accepting the requests authorized by allow.
+func newGuardedDaemon(t *testing.T, daemon *transmissionrpctest.Server, allow func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: daemon.URL().Host})
+	guarded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allow(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(guarded.Close)
+	return guarded
+}
+
+func TestAuthenticators(t *testing.T) {
+	daemon := newTestDaemon(t)
+	for name, testCase := range map[string]struct {
+		allow         func(r *http.Request) bool
+		authenticator transmissionrpc.Authenticator
+	}{
+		"basic": {
+			allow: func(r *http.Request) bool {
+				username, password, ok := r.BasicAuth()
+				return ok && username == "admin" && password == "secret"
+			},
+			authenticator: transmissionrpc.BasicAuth("admin", "secret"),
+		},
+		"bearer": {
+			allow: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer token"
+			},
+			authenticator: transmissionrpc.BearerToken("token"),
+		},
+		"header": {
+			allow: func(r *http.Request) bool {
+				return r.Header.Get("X-Api-Key") == "key"
+			},
+			authenticator: transmissionrpc.HeaderAuth("X-Api-Key", "key"),
+		},
+	} {
+		guarded := newGuardedDaemon(t, daemon, testCase.allow)
+		endpoint, _ := url.Parse(guarded.URL + transmissionrpctest.RPCPath)
+		// without credentials
+		client, err := transmissionrpc.New(endpoint, nil)
+		if err != nil {
+			t.Fatalf("%s: can't create client: %v", name, err)
+		}
+		if _, err = client.SessionStats(context.Background()); !errors.Is(err, transmissionrpc.ErrUnauthorized) {
+			t.Fatalf("%s: expected ErrUnauthorized without credentials, got %v", name, err)
+		}
+		// with credentials
+		if client, err = transmissionrpc.New(endpoint, &transmissionrpc.Config{
+			Authenticator: testCase.authenticator,
+		}); err != nil {
+			t.Fatalf("%s: can't create client: %v", name, err)
+		}
+		if _, err = client.SessionStats(context.Background()); err != nil {
+			t.Fatalf("%s: authenticated call failed: %v", name, err)
+		}
+	}
+}
+
+func TestAuthenticatorError(t *testing.T) {
+	daemon := newTestDaemon(t)
+	failure := errors.New("token expired")
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		Authenticator: transmissionrpc.AuthenticatorFunc(func(req *http.Request) error {
+			return failure
+		}),
+	})
+	if _, err := client.SessionStats(context.Background()); !errors.Is(err, failure) {
+		t.Fatalf("expected the authenticator error, got %v", err)
+	}
+	if len(daemon.Requests()) != 0 {
+		t.Fatal("unauthenticated requests must not be sent")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	daemon := newTestDaemon(t)
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: daemon.URL().Host})
+	secured := httptest.NewUnstartedServer(proxy)
+	secured.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	secured.StartTLS()
+	t.Cleanup(secured.Close)
+	endpoint, _ := url.Parse(secured.URL + transmissionrpctest.RPCPath)
+	ctx := context.Background()
+	// unknown authority
+	client, err := transmissionrpc.New(endpoint, nil)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	if _, err = client.SessionStats(ctx); err == nil {
+		t.Fatal("expected the self signed certificate to be rejected")
+	}
+	// trusted authority
+	roots := x509.NewCertPool()
+	roots.AddCert(secured.Certificate())
+	if client, err = transmissionrpc.New(endpoint, &transmissionrpc.Config{
+		TLS: &transmissionrpc.TLSConfig{RootCAs: roots},
+	}); err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("call with the trusted authority failed: %v", err)
+	}
+	// TLS options only apply to the default HTTP client
+	if _, err = transmissionrpc.New(endpoint, &transmissionrpc.Config{
+		TLS:          &transmissionrpc.TLSConfig{RootCAs: roots},
+		CustomClient: http.DefaultClient,
+	}); err == nil {
+		t.Fatal("expected TLS options with a custom HTTP client to be rejected")
+	}
+}
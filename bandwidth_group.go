@@ -27,10 +27,15 @@ type BandwidthGroup struct {
 // https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#482-bandwidth-group-accessor-group-get
 // Testing always yield the full list of bandwidth groups, even when submitting a filter.
 func (c *Client) BandwidthGroupGet(ctx context.Context, groups []string) (bandwidthGroups []BandwidthGroup, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	var (
 		filter string
 		answer bandwidthGroupGetAnswer
 	)
+	if err = c.requireRPCVersion(ctx, "'group-get' rpc method", bandwidthGroupsRPCVersion); err != nil {
+		return
+	}
 	if len(groups) > 0 {
 		filter = strings.Join(groups, ",")
 	}
@@ -54,10 +59,15 @@ type bandwidthGroupGetAnswer struct {
 
 // BandwidthGroupSet applies a list of mutator(s) to a bandwidth group.
 func (c *Client) BandwidthGroupSet(ctx context.Context, bwGroup BandwidthGroup) (err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	// Validate
 	if bwGroup.Name == "" {
 		return errors.New("Bandwidth group must have a name")
 	}
+	if err = c.requireRPCVersion(ctx, "'group-set' rpc method", bandwidthGroupsRPCVersion); err != nil {
+		return
+	}
 	// Send payload
 	if err = c.rpcCall(ctx, "group-set", bwGroup, nil); err != nil {
 		err = fmt.Errorf("'group-set' rpc method failed: %w", err)
@@ -0,0 +1,42 @@
+package transmissionrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+/*
+	Raw RPC calls
+	For the methods (or arguments) this library does not wrap (yet).
+	https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md
+*/
+
+// Call executes any RPC method: arguments (can be nil) are marshalled as the request arguments and the
+// answer arguments are unmarshalled into result (can be nil to ignore them). The call goes through the
+// same session id handling, tag verification, retry policy, interceptors and error wrapping than
+// the typed methods.
+func (c *Client) Call(ctx context.Context, method string, arguments, result interface{}) (err error) {
+	if method == "" {
+		return errors.New("method can't be empty")
+	}
+	if err = c.rpcCall(ctx, method, arguments, result); err != nil {
+		err = fmt.Errorf("'%s' rpc method failed: %w", method, err)
+	}
+	return
+}
+
+// CallRaw is like Call() but with raw JSON arguments (can be empty) and answer arguments.
+func (c *Client) CallRaw(ctx context.Context, method string, arguments json.RawMessage) (result json.RawMessage, err error) {
+	var args interface{}
+	if len(arguments) > 0 {
+		if !json.Valid(arguments) {
+			err = errors.New("arguments are not valid JSON")
+			return
+		}
+		args = arguments
+	}
+	err = c.Call(ctx, method, args, &result)
+	return
+}
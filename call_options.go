@@ -0,0 +1,131 @@
+package transmissionrpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+/*
+	Call options
+	Per call settings carried by the context, applied to every method of the client.
+*/
+
+// CallOption customizes the calls made with a context, see WithCallOptions().
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout     time.Duration
+	headers     []headerOption
+	noCSRFRetry bool
+	requestID   string
+	tableFormat *bool
+}
+
+// CallTimeout bounds each method call made with the options: retries, rate limit waits, session id
+// handshake and the RPC calls it may need (server version discovery, compatibility emulations, etc...)
+// included.
+func CallTimeout(timeout time.Duration) CallOption {
+	return func(opts *callOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// CallHeader sets a HTTP header on the requests, replacing the value set by the client if any
+// (User-Agent for example). Unlike WithHeader(), it does not add a value to the existing ones.
+func CallHeader(key, value string) CallOption {
+	return func(opts *callOptions) {
+		opts.headers = append(opts.headers, headerOption{key: key, value: value, replace: true})
+	}
+}
+
+// headerOption is a header set or added to the requests, applied in the order of the options.
+type headerOption struct {
+	key     string
+	value   string
+	replace bool
+}
+
+// NoCSRFRetry disables the transparent retry of the requests rejected because of an outdated
+// session id: the 409 answer is returned as an HTTPStatusCode error instead. The new session id
+// is still stored for the next calls. The initial handshake of a client without session id yet
+// is not affected.
+func NoCSRFRetry() CallOption {
+	return func(opts *callOptions) {
+		opts.noCSRFRetry = true
+	}
+}
+
+// RequestID attaches an identifier to the calls, available to interceptors and logs with
+// RequestIDFromContext() and within CallInfo. It is not sent to the daemon.
+func RequestID(id string) CallOption {
+	return func(opts *callOptions) {
+		opts.requestID = id
+	}
+}
+
+// TableFormat enables or disables the "table" format of the torrent-get requests, overriding the
+// TableFormat value of the client Config. Daemons older than RPC v16 still get the objects format.
+func TableFormat(enabled bool) CallOption {
+	return func(opts *callOptions) {
+		opts.tableFormat = &enabled
+	}
+}
+
+type callOptionsContextKey struct{}
+
+// WithCallOptions returns a context applying the given options to the calls made with it.
+// Options already carried by ctx are kept unless overridden.
+func WithCallOptions(ctx context.Context, options ...CallOption) context.Context {
+	var opts callOptions
+	if parent, ok := ctx.Value(callOptionsContextKey{}).(*callOptions); ok {
+		opts = *parent
+		// the parent ones must not be shared by the appends
+		opts.headers = append([]headerOption(nil), parent.headers...)
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return context.WithValue(ctx, callOptionsContextKey{}, &opts)
+}
+
+func callOptionsFromContext(ctx context.Context) (opts callOptions) {
+	if carried, ok := ctx.Value(callOptionsContextKey{}).(*callOptions); ok {
+		opts = *carried
+	}
+	return
+}
+
+// RequestIDFromContext returns the request id attached with the RequestID() option, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	return callOptionsFromContext(ctx).requestID
+}
+
+type callTimeoutContextKey struct{}
+
+// withCallTimeout starts the CallTimeout() option of ctx, if any. The returned context is marked as
+// already bounded: the RPC calls nested within the method call (server version discovery,
+// compatibility emulations, etc...) keep its deadline instead of starting their own. Methods making
+// several RPC calls start it on entry, rpcCall() does it for the others.
+func withCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Value(callTimeoutContextKey{}) != nil {
+		return ctx, func() {}
+	}
+	timeout := callOptionsFromContext(ctx).timeout
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return context.WithValue(ctx, callTimeoutContextKey{}, struct{}{}), cancel
+}
+
+// applyHeaders sets or adds the headers of the CallHeader() and WithHeader() options of ctx.
+func applyHeaders(ctx context.Context, headers http.Header) {
+	for _, header := range callOptionsFromContext(ctx).headers {
+		if header.replace {
+			headers.Set(header.key, header.value)
+		} else {
+			headers.Add(header.key, header.value)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestCallTimeout(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	daemon.SetLatency(time.Second)
+	start := time.Now()
+	ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.CallTimeout(50*time.Millisecond))
+	if _, err := client.SessionStats(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("expected the call to be cut short, took %v", elapsed)
+	}
+}
+
+func TestCallTimeoutBoundsTheMethodCall(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.SetLatency(50 * time.Millisecond)
+	client := newTestClient(t, daemon, nil)
+	// labels needs the server version: handshake, session-get and torrent-get take 150ms
+	ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.CallTimeout(120*time.Millisecond))
+	_, err := client.TorrentGet(ctx, []string{"id", "labels"}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestCallTimeoutAppliesToEachMethodCall(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.AddTorrent(map[string]interface{}{"name": "debian.iso"})
+	client := newTestClient(t, daemon, nil)
+	ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.CallTimeout(100*time.Millisecond))
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	// the window of the first call is over: the next ones get their own
+	time.Sleep(150 * time.Millisecond)
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("call made later with the same context failed: %v", err)
+	}
+	if _, err := client.TorrentGet(ctx, []string{"id", "labels"}, nil); err != nil {
+		t.Fatalf("call made later with the same context failed: %v", err)
+	}
+	it, err := client.TorrentGetIterator(ctx, []string{"id", "name"}, nil)
+	if err != nil {
+		t.Fatalf("iterator made later with the same context failed: %v", err)
+	}
+	for it.Next() {
+	}
+	it.Close()
+	if err = it.Err(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+}
+
+func TestNoCSRFRetry(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.NoCSRFRetry())
+	// no session id yet: the handshake must still happen
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	// outdated session id: the 409 is returned
+	daemon.RotateSessionID()
+	_, err := client.SessionStats(ctx)
+	var statusCode transmissionrpc.HTTPStatusCode
+	if !errors.As(err, &statusCode) || statusCode != http.StatusConflict {
+		t.Fatalf("expected a 409 HTTPStatusCode, got %v", err)
+	}
+	// the new session id has been stored
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("call with the new session id failed: %v", err)
+	}
+}
+
+// headersRecorder records the headers of the requests going through it.
+type headersRecorder struct {
+	mutex   sync.Mutex
+	headers []http.Header
+}
+
+func (hr *headersRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	hr.mutex.Lock()
+	hr.headers = append(hr.headers, req.Header.Clone())
+	hr.mutex.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (hr *headersRecorder) last() http.Header {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+	return hr.headers[len(hr.headers)-1]
+}
+
+func TestCallHeader(t *testing.T) {
+	daemon := newTestDaemon(t)
+	recorder := new(headersRecorder)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		CustomClient: &http.Client{Transport: recorder},
+		UserAgent:    "client-agent",
+	})
+	ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.CallHeader("User-Agent", "call-agent"))
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if agents := recorder.last().Values("User-Agent"); len(agents) != 1 || agents[0] != "call-agent" {
+		t.Fatalf("expected User-Agent to be [call-agent], got %v", agents)
+	}
+	// options are only applied to the calls made with the context
+	if _, err := client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if agents := recorder.last().Values("User-Agent"); len(agents) != 1 || agents[0] != "client-agent" {
+		t.Fatalf("expected User-Agent to be [client-agent], got %v", agents)
+	}
+}
+
+func TestCallHeaderAndWithHeader(t *testing.T) {
+	daemon := newTestDaemon(t)
+	recorder := new(headersRecorder)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		CustomClient: &http.Client{Transport: recorder},
+		UserAgent:    "client-agent",
+	})
+	ctx := transmissionrpc.WithHeader(context.Background(), "X-Trace", "a")
+	ctx = transmissionrpc.WithCallOptions(ctx, transmissionrpc.CallHeader("User-Agent", "call-agent"))
+	ctx = transmissionrpc.WithHeader(ctx, "X-Trace", "b")
+	ctx = transmissionrpc.WithHeader(ctx, "User-Agent", "extra-agent")
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	headers := recorder.last()
+	if trace := headers.Values("X-Trace"); len(trace) != 2 || trace[0] != "a" || trace[1] != "b" {
+		t.Fatalf("expected X-Trace to be [a b], got %v", trace)
+	}
+	if agents := headers.Values("User-Agent"); len(agents) != 2 || agents[0] != "call-agent" || agents[1] != "extra-agent" {
+		t.Fatalf("expected User-Agent to be [call-agent extra-agent], got %v", agents)
+	}
+	// the parent context is not modified by its children
+	parent := transmissionrpc.WithHeader(context.Background(), "X-Trace", "parent")
+	_ = transmissionrpc.WithHeader(parent, "X-Trace", "child")
+	if _, err := client.SessionStats(parent); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if trace := recorder.last().Values("X-Trace"); len(trace) != 1 || trace[0] != "parent" {
+		t.Fatalf("expected X-Trace to be [parent], got %v", trace)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	daemon := newTestDaemon(t)
+	var infos []transmissionrpc.CallInfo
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		Interceptors: []transmissionrpc.Interceptor{
+			transmissionrpc.Observe(func(ctx context.Context, info transmissionrpc.CallInfo) {
+				infos = append(infos, info)
+			}),
+		},
+	})
+	ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.RequestID("req-1"))
+	if id := transmissionrpc.RequestIDFromContext(ctx); id != "req-1" {
+		t.Fatalf("expected req-1 from the context, got %q", id)
+	}
+	// children keep the options of their parent
+	ctx = transmissionrpc.WithCallOptions(ctx, transmissionrpc.CallHeader("X-Trace", "a"))
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if _, err := client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(infos) != 2 || infos[0].RequestID != "req-1" || infos[1].RequestID != "" {
+		t.Fatalf("unexpected observed calls: %+v", infos)
+	}
+}
+
+func TestTableFormatCallOption(t *testing.T) {
+	for _, tc := range []struct {
+		config bool
+		option bool
+		format string
+	}{
+		{config: false, option: true, format: "table"},
+		{config: true, option: false, format: ""},
+	} {
+		daemon := newTestDaemon(t)
+		daemon.AddTorrent(map[string]interface{}{"name": "debian.iso"})
+		client := newTestClient(t, daemon, &transmissionrpc.Config{TableFormat: tc.config})
+		ctx := transmissionrpc.WithCallOptions(context.Background(), transmissionrpc.TableFormat(tc.option))
+		torrents, err := client.TorrentGet(ctx, []string{"id", "name"}, nil)
+		if err != nil {
+			t.Fatalf("config %v, option %v: torrent-get failed: %v", tc.config, tc.option, err)
+		}
+		if len(torrents) != 1 || *torrents[0].Name != "debian.iso" {
+			t.Fatalf("config %v, option %v: unexpected torrents: %+v", tc.config, tc.option, torrents)
+		}
+		if format := lastTorrentGetFormat(t, daemon); format != tc.format {
+			t.Fatalf("config %v, option %v: expected format %q, got %q", tc.config, tc.option, tc.format, format)
+		}
+		// the calls without the option keep the client default
+		if _, err = client.TorrentGet(context.Background(), []string{"id"}, nil); err != nil {
+			t.Fatalf("config %v: torrent-get failed: %v", tc.config, err)
+		}
+		if format := lastTorrentGetFormat(t, daemon); format == tc.format {
+			t.Fatalf("config %v: expected the default format, got %q", tc.config, format)
+		}
+	}
+}
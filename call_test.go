@@ -0,0 +1,89 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestCall(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.SetPortOpen(true)
+	var intercepted []string
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		Interceptors: []transmissionrpc.Interceptor{
+			func(ctx context.Context, method string, arguments, result interface{}, next transmissionrpc.Invoker) error {
+				intercepted = append(intercepted, method)
+				return next(ctx, method, arguments, result)
+			},
+		},
+	})
+	ctx := context.Background()
+	var result struct {
+		PortIsOpen bool `json:"port-is-open"`
+	}
+	if err := client.Call(ctx, "port-test", nil, &result); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if !result.PortIsOpen {
+		t.Fatal("expected the port to be reported as open")
+	}
+	// arguments are sent as is
+	if err := client.Call(ctx, "session-set", map[string]interface{}{"download-dir": "/data"}, nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if session := daemon.Session(); session["download-dir"] != "/data" {
+		t.Fatalf("expected the session to be updated, got %v", session["download-dir"])
+	}
+	if len(intercepted) != 2 || intercepted[0] != "port-test" || intercepted[1] != "session-set" {
+		t.Fatalf("expected raw calls to go through the interceptors, got %v", intercepted)
+	}
+	// errors are wrapped like the typed methods ones
+	err := client.Call(ctx, "not-a-method", nil, nil)
+	if !errors.Is(err, transmissionrpc.ErrMethodNotRecognized) {
+		t.Fatalf("expected ErrMethodNotRecognized, got %v", err)
+	}
+	var rpcErr *transmissionrpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Method != "not-a-method" {
+		t.Fatalf("expected a RPCError for the method, got %v", err)
+	}
+	if err = client.Call(ctx, "", nil, nil); err == nil {
+		t.Fatal("expected an empty method to be rejected")
+	}
+}
+
+func TestCallRaw(t *testing.T) {
+	daemon := newTestDaemon(t)
+	id := daemon.AddTorrent(map[string]interface{}{"name": "raw"})
+	client := newTestClient(t, daemon, nil)
+	ctx := context.Background()
+	result, err := client.CallRaw(ctx, "torrent-get", json.RawMessage(`{"fields":["id","name"]}`))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	var decoded struct {
+		Torrents []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"torrents"`
+	}
+	if err = json.Unmarshal(result, &decoded); err != nil {
+		t.Fatalf("can't decode raw result %s: %v", result, err)
+	}
+	if len(decoded.Torrents) != 1 || decoded.Torrents[0].ID != id || decoded.Torrents[0].Name != "raw" {
+		t.Fatalf("unexpected raw result: %s", result)
+	}
+	// no arguments
+	if _, err = client.CallRaw(ctx, "session-stats", nil); err != nil {
+		t.Fatalf("call without arguments failed: %v", err)
+	}
+	if _, err = client.CallRaw(ctx, "session-set", json.RawMessage(`{"download-dir":`)); err == nil {
+		t.Fatal("expected invalid JSON arguments to be rejected")
+	}
+	if count := countRequests(daemon, "session-set"); count != 0 {
+		t.Fatalf("invalid arguments must not be sent, got %d session-set", count)
+	}
+}
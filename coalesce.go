@@ -0,0 +1,192 @@
+package transmissionrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+	Reads coalescing
+	Concurrent identical read calls share a single HTTP round trip, session-get answers can be cached.
+*/
+
+// coalescableMethods are the read methods whose concurrent identical calls can share an answer.
+var coalescableMethods = map[string]bool{
+	"torrent-get":   true,
+	"session-get":   true,
+	"session-stats": true,
+	"group-get":     true,
+	"free-space":    true,
+}
+
+// readCoalescer shares the raw answers of the read calls: each caller still decodes its own copy.
+type readCoalescer struct {
+	coalesce   bool
+	sessionTTL time.Duration
+	access     sync.Mutex
+	inFlight   map[string]*sharedAnswer
+	cache      map[string]*sharedAnswer // session-get answers
+	generation uint64                   // incremented by each invalidation of the cache
+}
+
+type sharedAnswer struct {
+	done     chan struct{}
+	body     []byte
+	tag      int
+	endpoint *rpcEndpoint // endpoint which served the answer
+	err      error
+	expires  time.Time
+}
+
+func newReadCoalescer(coalesce bool, sessionTTL time.Duration) *readCoalescer {
+	return &readCoalescer{
+		coalesce:   coalesce,
+		sessionTTL: sessionTTL,
+		inFlight:   make(map[string]*sharedAnswer),
+		cache:      make(map[string]*sharedAnswer),
+	}
+}
+
+// invalidate drops the cached answers: the daemon state may have changed.
+func (rc *readCoalescer) invalidate() {
+	if rc == nil || rc.sessionTTL <= 0 {
+		return
+	}
+	rc.access.Lock()
+	rc.generation++
+	rc.cache = make(map[string]*sharedAnswer)
+	rc.access.Unlock()
+}
+
+func (rc *readCoalescer) cached(key string) (answer *sharedAnswer, generation uint64) {
+	defer rc.access.Unlock()
+	rc.access.Lock()
+	if answer = rc.cache[key]; answer != nil && time.Now().After(answer.expires) {
+		delete(rc.cache, key)
+		answer = nil
+	}
+	return answer, rc.generation
+}
+
+func (rc *readCoalescer) store(key string, answer *sharedAnswer, generation uint64) {
+	defer rc.access.Unlock()
+	rc.access.Lock()
+	// a write happened while fetching: the answer may already be outdated
+	if generation == rc.generation {
+		answer.expires = time.Now().Add(rc.sessionTTL)
+		rc.cache[key] = answer
+	}
+}
+
+// sharedRequest executes a read call, sharing its answer with the identical concurrent calls
+// and/or the session-get cache.
+func (c *Client) sharedRequest(ctx context.Context, protocol wireProtocol, method string, arguments, result interface{}, retry bool) (err error) {
+	key, err := coalescingKey(ctx, protocol, method, arguments)
+	if err != nil {
+		return
+	}
+	caching := method == "session-get" && c.reads.sessionTTL > 0
+	var generation uint64
+	if caching {
+		var answer *sharedAnswer
+		if answer, generation = c.reads.cached(key); answer != nil {
+			return protocol.decodeAnswer(method, bytes.NewReader(answer.body), result, answer.tag)
+		}
+	}
+	var answer *sharedAnswer
+	if c.reads.coalesce {
+		if answer, err = c.reads.join(ctx, key, func() *sharedAnswer {
+			return c.fetchAnswer(ctx, protocol, method, arguments, retry)
+		}); err != nil {
+			return
+		}
+		// the callers sharing the answer were served by the endpoint of the leader one
+		if answer.endpoint != nil {
+			recordServedEndpoint(ctx, answer.endpoint)
+		}
+	} else {
+		answer = c.fetchAnswer(ctx, protocol, method, arguments, retry)
+	}
+	if answer.err != nil {
+		return answer.err
+	}
+	if err = protocol.decodeAnswer(method, bytes.NewReader(answer.body), result, answer.tag); err == nil && caching {
+		c.reads.store(key, answer, generation)
+	}
+	return
+}
+
+// join returns the answer of the identical call in flight, or executes fetch if there is none.
+func (rc *readCoalescer) join(ctx context.Context, key string, fetch func() *sharedAnswer) (answer *sharedAnswer, err error) {
+	for {
+		rc.access.Lock()
+		shared, found := rc.inFlight[key]
+		if !found {
+			shared = &sharedAnswer{done: make(chan struct{})}
+			rc.inFlight[key] = shared
+			rc.access.Unlock()
+			// leader
+			fetched := fetch()
+			shared.body, shared.tag, shared.endpoint, shared.err = fetched.body, fetched.tag, fetched.endpoint, fetched.err
+			rc.access.Lock()
+			delete(rc.inFlight, key)
+			rc.access.Unlock()
+			close(shared.done)
+			return shared, nil
+		}
+		rc.access.Unlock()
+		select {
+		case <-shared.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// the leader context being done is not our failure: try again
+		if (errors.Is(shared.err, context.Canceled) || errors.Is(shared.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+			continue
+		}
+		return shared, nil
+	}
+}
+
+// fetchAnswer sends the request and reads the whole answer body.
+func (c *Client) fetchAnswer(ctx context.Context, protocol wireProtocol, method string, arguments interface{}, retry bool) (answer *sharedAnswer) {
+	answer = new(sharedAnswer)
+	resp, tag, served, err := c.send(ctx, protocol, method, arguments, retry)
+	if err != nil {
+		answer.err = err
+		return
+	}
+	defer resp.Body.Close()
+	if answer.body, err = io.ReadAll(resp.Body); err != nil {
+		answer.err = fmt.Errorf("can't read answer: %w", err)
+		return
+	}
+	answer.tag = tag
+	answer.endpoint = served
+	return
+}
+
+// coalescingKey identifies identical calls: same wire protocol, method, arguments, injected headers
+// and session id handling.
+func coalescingKey(ctx context.Context, protocol wireProtocol, method string, arguments interface{}) (key string, err error) {
+	payload, err := json.Marshal(arguments)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal request arguments: %w", err)
+		return
+	}
+	injected := make(http.Header)
+	applyHeaders(ctx, injected)
+	headers, err := json.Marshal(injected)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal request headers: %w", err)
+		return
+	}
+	return fmt.Sprintf("%T\x00%s\x00%s\x00%s\x00%t", protocol, method, payload, headers, callOptionsFromContext(ctx).noCSRFRetry), nil
+}
@@ -0,0 +1,155 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestCoalesceReads(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.AddTorrent(map[string]interface{}{"name": "ubuntu.iso"})
+	client := newTestClient(t, daemon, &transmissionrpc.Config{CoalesceReads: true})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	daemon.SetLatency(50 * time.Millisecond)
+	var (
+		mutex   sync.Mutex
+		results [][]transmissionrpc.Torrent
+	)
+	err := concurrently(10, func() error {
+		torrents, err := client.TorrentGet(context.Background(), []string{"id", "name"}, nil)
+		mutex.Lock()
+		results = append(results, torrents)
+		mutex.Unlock()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if count := countRequests(daemon, "torrent-get"); count != 1 {
+		t.Fatalf("expected a single torrent-get request, got %d", count)
+	}
+	// each caller decodes its own copy
+	*results[0][0].Name = "modified"
+	for _, torrents := range results[1:] {
+		if len(torrents) != 1 || *torrents[0].Name != "ubuntu.iso" {
+			t.Fatalf("unexpected shared answer: %+v", torrents)
+		}
+	}
+	// different arguments are not coalesced
+	fieldsSets := make(chan []string, 2)
+	fieldsSets <- []string{"id"}
+	fieldsSets <- []string{"name"}
+	err = concurrently(2, func() error {
+		_, err := client.TorrentGet(context.Background(), <-fieldsSets, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if count := countRequests(daemon, "torrent-get"); count != 3 {
+		t.Fatalf("expected 3 torrent-get requests, got %d", count)
+	}
+}
+
+func TestCoalescedReadsEndpoint(t *testing.T) {
+	daemon := newTestDaemon(t)
+	var (
+		mutex     sync.Mutex
+		endpoints []string
+	)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		CoalesceReads: true,
+		Interceptors: []transmissionrpc.Interceptor{
+			transmissionrpc.Observe(func(ctx context.Context, info transmissionrpc.CallInfo) {
+				mutex.Lock()
+				endpoints = append(endpoints, info.Endpoint)
+				mutex.Unlock()
+			}),
+		},
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	mutex.Lock()
+	endpoints = nil
+	mutex.Unlock()
+	daemon.SetLatency(50 * time.Millisecond)
+	err := concurrently(5, func() error {
+		_, err := client.SessionStats(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if count := countRequests(daemon, "session-stats"); count != 1 {
+		t.Fatalf("expected a single session-stats request, got %d", count)
+	}
+	// the callers sharing the answer report the endpoint which served it too
+	if len(endpoints) != 5 {
+		t.Fatalf("expected 5 observed calls, got %d", len(endpoints))
+	}
+	for _, endpoint := range endpoints {
+		if endpoint != daemon.URL().String() {
+			t.Fatalf("expected all the calls to be served by %s, got %v", daemon.URL(), endpoints)
+		}
+	}
+}
+
+func TestSessionGetCache(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{SessionGetCacheTTL: time.Minute})
+	ctx := context.Background()
+	fields := []string{"download-dir"}
+	get := func(expected int) {
+		t.Helper()
+		if _, err := client.SessionArgumentsGet(ctx, fields); err != nil {
+			t.Fatalf("session-get failed: %v", err)
+		}
+		if count := countRequests(daemon, "session-get"); count != expected {
+			t.Fatalf("expected %d session-get requests, got %d", expected, count)
+		}
+	}
+	get(1)
+	get(1)
+	// writes drop the cache
+	downloadDir := "/data"
+	if err := client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{DownloadDir: &downloadDir}); err != nil {
+		t.Fatalf("session-set failed: %v", err)
+	}
+	get(2)
+	args, err := client.SessionArgumentsGet(ctx, fields)
+	if err != nil || args.DownloadDir == nil || *args.DownloadDir != downloadDir {
+		t.Fatalf("expected the new download dir from the cache, got %+v (err: %v)", args.DownloadDir, err)
+	}
+	// a daemon restart (detected by the next request reaching it) drops it too
+	daemon.RotateSessionID()
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("session-stats failed: %v", err)
+	}
+	get(3)
+	get(3)
+}
+
+func TestSessionGetCacheExpiration(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{SessionGetCacheTTL: time.Minute})
+	get := func(expected int) {
+		t.Helper()
+		if _, err := client.SessionArgumentsGet(context.Background(), []string{"download-dir"}); err != nil {
+			t.Fatalf("session-get failed: %v", err)
+		}
+		if count := countRequests(daemon, "session-get"); count != expected {
+			t.Fatalf("expected %d session-get requests, got %d", expected, count)
+		}
+	}
+	get(1)
+	get(1)
+	client.AgeSessionGetCache(time.Minute)
+	get(2)
+}
@@ -0,0 +1,219 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+	Compatibility mode
+	Transparent translations for daemons older than RPC v17 (transmission 2.9x and 3.x), see Config.Compatibility.
+	https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#5-protocol-versions
+*/
+
+// emulatedTorrentFields contains the torrent-get fields that can be computed from older fields.
+var emulatedTorrentFields = map[string][]string{
+	"file-count":      {"wanted"},
+	"percentComplete": {"haveValid", "haveUnchecked", "totalSize"},
+	"trackerList":     {"trackers"},
+}
+
+// prepareTorrentFields returns the fields to actually request to the daemon along with the fields
+// that will have to be emulated from them (compatibility mode only). It fails if a field is not
+// supported by the daemon and can not be emulated.
+func (c *Client) prepareTorrentFields(ctx context.Context, fields []string) (request, emulated []string, err error) {
+	request = fields
+	if c.compat && needsEmulation(fields) {
+		var version ServerVersion
+		if version, err = c.ServerVersion(ctx); err != nil {
+			err = fmt.Errorf("can't check the torrent fields supported by the server: %w", err)
+			return
+		}
+		request = make([]string, 0, len(fields))
+		var sources []string
+		for _, field := range fields {
+			if rpcVersion, found := torrentFieldsRPCVersions[field]; found && !version.Supports(rpcVersion) {
+				if fieldSources, emulable := emulatedTorrentFields[field]; emulable {
+					emulated = append(emulated, field)
+					sources = append(sources, fieldSources...)
+					continue
+				}
+			}
+			request = append(request, field)
+		}
+		request = mergeFields(request, sources)
+	}
+	err = c.checkTorrentFields(ctx, request)
+	return
+}
+
+func needsEmulation(fields []string) bool {
+	for _, field := range fields {
+		if _, found := emulatedTorrentFields[field]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// emulateTorrentFields computes the emulated fields of a torrent from their sources.
+func emulateTorrentFields(torrent *Torrent, emulated []string) {
+	for _, field := range emulated {
+		switch field {
+		case "file-count":
+			if torrent.Wanted != nil {
+				fileCount := int64(len(torrent.Wanted))
+				torrent.FileCount = &fileCount
+			}
+		case "percentComplete":
+			if torrent.HaveValid != nil && torrent.HaveUnchecked != nil && torrent.TotalSize != nil {
+				var percentComplete float64
+				if totalSize := torrent.TotalSize.Byte(); totalSize > 0 {
+					percentComplete = float64(*torrent.HaveValid+*torrent.HaveUnchecked) / totalSize
+				}
+				torrent.PercentComplete = &percentComplete
+			}
+		case "trackerList":
+			if torrent.Trackers != nil {
+				trackerList := buildTrackerList(torrent.Trackers)
+				torrent.TrackerList = &trackerList
+			}
+		}
+	}
+}
+
+// buildTrackerList formats trackers as RPC v17 does: one announce URL per line and a blank line between tiers.
+func buildTrackerList(trackers []Tracker) string {
+	sorted := make([]Tracker, len(trackers))
+	copy(sorted, trackers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tier < sorted[j].Tier
+	})
+	var builder strings.Builder
+	for index, tracker := range sorted {
+		if index > 0 && tracker.Tier != sorted[index-1].Tier {
+			builder.WriteByte('\n')
+		}
+		builder.WriteString(tracker.Announce)
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+/*
+	Torrent mutators
+*/
+
+// torrentSet sends the torrent-set arguments (ids included), translating trackerList into
+// trackerRemove/trackerAdd calls for old daemons when the compatibility mode is enabled.
+func (c *Client) torrentSet(ctx context.Context, arguments map[string]interface{}) (err error) {
+	if trackerList, found := arguments["trackerList"]; found && c.compat {
+		var version ServerVersion
+		if version, err = c.ServerVersion(ctx); err != nil {
+			return fmt.Errorf("can't check if trackerList is supported by the server: %w", err)
+		}
+		if !version.Supports(torrentSetRPCVersions["trackerList"]) {
+			trackerListValue, _ := trackerList.(*string)
+			if trackerListValue == nil {
+				return errors.New("trackerList must be a string")
+			}
+			remaining := make(map[string]interface{}, len(arguments))
+			for key, value := range arguments {
+				if key != "trackerList" {
+					remaining[key] = value
+				}
+			}
+			// fail fast before mutating anything
+			if err = c.checkTorrentSetArguments(ctx, remaining); err != nil {
+				return
+			}
+			if err = c.replaceTrackers(ctx, arguments["ids"], *trackerListValue); err != nil {
+				return fmt.Errorf("can't emulate trackerList: %w", err)
+			}
+			if len(remaining) == 1 {
+				// only ids left: nothing else to set
+				return
+			}
+			return c.rpcCall(ctx, "torrent-set", remaining, nil)
+		}
+	}
+	if err = c.checkTorrentSetArguments(ctx, arguments); err != nil {
+		return
+	}
+	return c.rpcCall(ctx, "torrent-set", arguments, nil)
+}
+
+// replaceTrackers makes the trackers of the targeted torrents match the announce URLs of trackerList,
+// using the trackerRemove and trackerAdd mutators available before RPC v17. Tiers are not kept: old
+// daemons add each tracker in its own tier.
+func (c *Client) replaceTrackers(ctx context.Context, ids interface{}, trackerList string) (err error) {
+	var wanted []string
+	for _, line := range strings.Split(trackerList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			wanted = append(wanted, line)
+		}
+	}
+	// Get the current trackers of each torrent
+	var result torrentGetResults
+	if err = c.rpcCall(ctx, "torrent-get", torrentGetAnyParams{
+		Fields: []string{"id", "trackers"},
+		IDs:    ids,
+	}, &result); err != nil {
+		return fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+	}
+	// Apply the differences torrent by torrent (trackers ids are scoped to their torrent)
+	for _, torrent := range result.Torrents {
+		if torrent.ID == nil {
+			continue
+		}
+		current := make(map[string]bool, len(torrent.Trackers))
+		var remove []int64
+		for _, tracker := range torrent.Trackers {
+			current[tracker.Announce] = true
+			if !containsString(wanted, tracker.Announce) {
+				remove = append(remove, tracker.ID)
+			}
+		}
+		var add []string
+		for _, announce := range wanted {
+			if !current[announce] {
+				add = append(add, announce)
+				current[announce] = true
+			}
+		}
+		if len(remove) > 0 {
+			if err = c.rpcCall(ctx, "torrent-set", map[string]interface{}{
+				"ids":           []int64{*torrent.ID},
+				"trackerRemove": remove,
+			}, nil); err != nil {
+				return fmt.Errorf("can't remove trackers of torrent %d: %w", *torrent.ID, err)
+			}
+		}
+		if len(add) > 0 {
+			if err = c.rpcCall(ctx, "torrent-set", map[string]interface{}{
+				"ids":        []int64{*torrent.ID},
+				"trackerAdd": add,
+			}, nil); err != nil {
+				return fmt.Errorf("can't add trackers to torrent %d: %w", *torrent.ID, err)
+			}
+		}
+	}
+	return
+}
+
+type torrentGetAnyParams struct {
+	Fields []string    `json:"fields"`
+	IDs    interface{} `json:"ids,omitempty"`
+}
+
+func containsString(list []string, value string) bool {
+	for _, candidate := range list {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
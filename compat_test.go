@@ -0,0 +1,140 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestCompatTrackerListEmulation(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	id := daemon.AddTorrent(map[string]interface{}{"name": "debian.iso"})
+	client := newTestClient(t, daemon, &transmissionrpc.Config{Compatibility: true})
+	// Replace the trackers
+	err := client.TorrentSet(context.Background(), transmissionrpc.TorrentSetPayload{
+		IDs:         []int64{id},
+		TrackerList: []string{"http://a.example.com/announce", "http://b.example.com/announce"},
+	})
+	if err != nil {
+		t.Fatalf("emulated trackerList failed: %v", err)
+	}
+	fields, _ := daemon.Torrent(id)
+	if trackers, _ := fields["trackers"].([]interface{}); len(trackers) != 2 {
+		t.Fatalf("expected 2 trackers, got %v", fields["trackers"])
+	}
+	for _, request := range daemon.Requests() {
+		var arguments map[string]json.RawMessage
+		_ = json.Unmarshal(request.Arguments, &arguments)
+		if _, found := arguments["trackerList"]; found {
+			t.Fatalf("trackerList sent to a RPC v16 daemon: %s", request.Arguments)
+		}
+	}
+}
+
+func TestCompatTrackerListFailsFast(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	id := daemon.AddTorrent(map[string]interface{}{"name": "debian.iso"})
+	client := newTestClient(t, daemon, &transmissionrpc.Config{Compatibility: true})
+	// group is not supported before RPC v17: nothing must be changed
+	group := "slow"
+	err := client.TorrentSet(context.Background(), transmissionrpc.TorrentSetPayload{
+		IDs:         []int64{id},
+		Group:       &group,
+		TrackerList: []string{"http://a.example.com/announce"},
+	})
+	if !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer, got %v", err)
+	}
+	if count := countRequests(daemon, "torrent-set"); count != 0 {
+		t.Fatalf("expected no torrent-set request, got %d", count)
+	}
+	fields, _ := daemon.Torrent(id)
+	if trackers, _ := fields["trackers"].([]interface{}); len(trackers) != 0 {
+		t.Fatalf("trackers modified: %v", fields["trackers"])
+	}
+}
+
+func TestCompatTorrentFieldsEmulation(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	id := daemon.AddTorrent(map[string]interface{}{
+		"name":          "debian.iso",
+		"wanted":        []interface{}{1, 0, 1},
+		"haveValid":     300,
+		"haveUnchecked": 100,
+		"totalSize":     1000,
+		"trackers": []interface{}{
+			map[string]interface{}{"announce": "http://b.example.com/announce", "tier": 1},
+			map[string]interface{}{"announce": "http://a.example.com/announce", "tier": 0},
+		},
+	})
+	ctx := context.Background()
+	fields := []string{"id", "file-count", "percentComplete", "trackerList"}
+	// without the compatibility mode the fields are rejected
+	client := newTestClient(t, daemon, nil)
+	if _, err := client.TorrentGet(ctx, fields, []int64{id}); !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer, got %v", err)
+	}
+	// with it they are computed from their older counterparts
+	client = newTestClient(t, daemon, &transmissionrpc.Config{Compatibility: true})
+	torrents, err := client.TorrentGet(ctx, fields, []int64{id})
+	if err != nil {
+		t.Fatalf("emulated fields failed: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expected 1 torrent, got %d", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.FileCount == nil || *torrent.FileCount != 3 {
+		t.Fatalf("unexpected file-count: %v", torrent.FileCount)
+	}
+	if torrent.PercentComplete == nil || *torrent.PercentComplete != 0.4 {
+		t.Fatalf("unexpected percentComplete: %v", torrent.PercentComplete)
+	}
+	expected := "http://a.example.com/announce\n\nhttp://b.example.com/announce\n"
+	if torrent.TrackerList == nil || *torrent.TrackerList != expected {
+		t.Fatalf("unexpected trackerList: %q", derefString(torrent.TrackerList))
+	}
+	for _, request := range daemon.Requests() {
+		var arguments struct {
+			Fields []string `json:"fields"`
+		}
+		_ = json.Unmarshal(request.Arguments, &arguments)
+		for _, field := range arguments.Fields {
+			if field == "file-count" || field == "percentComplete" || field == "trackerList" {
+				t.Fatalf("emulated field %s sent to a RPC v16 daemon", field)
+			}
+		}
+	}
+}
+
+func TestCompatSessionFieldsGating(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	client := newTestClient(t, daemon, &transmissionrpc.Config{Compatibility: true})
+	ctx := context.Background()
+	if _, err := client.SessionArgumentsGet(ctx, []string{"default-trackers"}); !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer, got %v", err)
+	}
+	err := client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{
+		DefaultTrackers: []string{"http://a.example.com/announce"},
+	})
+	if !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer, got %v", err)
+	}
+	if count := countRequests(daemon, "session-set"); count != 0 {
+		t.Fatalf("unsupported fields must not be sent, got %d session-set", count)
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -2,10 +2,12 @@ package transmissionrpc
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
@@ -13,7 +15,7 @@ import (
 
 const (
 	// RPCVersion indicates the exact transmission RPC version this library is build against
-	RPCVersion       = 17
+	RPCVersion       = 18
 	defaultUserAgent = "github.com/hekmon/transmissionrpc"
 )
 
@@ -23,34 +25,125 @@ type Config struct {
 	UserAgent string
 	// Client is set to a clean and isolated client if not provided
 	CustomClient *http.Client
+	// TableFormat makes all torrent-get requests use the "table" format: a header row with the
+	// fields names followed by one array per torrent. It is much smaller on the wire than the
+	// default objects format when requesting a lot of torrents. Decoded values are identical.
+	// Daemons older than RPC v16 do not support it: the objects format is used with them.
+	// It is the default of the calls, see the TableFormat() call option.
+	TableFormat bool
+	// RetryPolicy, if set, allows to retry the RPC calls failing because of transient errors.
+	// Check DefaultRetryPolicy() for sensible values.
+	RetryPolicy *RetryPolicy
+	// Interceptors are called around every RPC call, the first one being the outermost.
+	Interceptors []Interceptor
+	// UnixSocket is the path of the unix socket the daemon RPC server listens on (rpc-bind-address
+	// set to "unix:/path"). The endpoint URL is still used for the HTTP requests (path, credentials).
+	// The socket can also be directly set within the endpoint URL: see New().
+	UnixSocket string
+	// Authenticator, if set, authenticates every HTTP request (see BasicAuth(), BearerToken() and
+	// HeaderAuth()). It avoids having credentials within the endpoint URL.
+	Authenticator Authenticator
+	// TLS allows to customize the TLS settings of the default HTTP client (https endpoints).
+	TLS *TLSConfig
+	// DisableFeatureGating disables the server version discovery: methods, fields and arguments
+	// requiring a RPC version newer than the daemon one are sent anyway instead of failing early
+	// with ErrUnsupportedByServer.
+	DisableFeatureGating bool
+	// Compatibility enables transparent translations for daemons older than RPC v17 (transmission
+	// 2.9x and 3.x): TorrentSetPayload.TrackerList is applied with trackerRemove/trackerAdd and the
+	// file-count, percentComplete and trackerList torrent fields are computed from older fields.
+	// Features that can not be emulated (bandwidth groups, etc...) fail with ErrUnsupportedByServer.
+	Compatibility bool
+	// Protocol selects the wire format: the legacy one by default. Transmission 4.1 and newer
+	// also speak JSON-RPC 2.0 (with snake_case names), the legacy format being deprecated.
+	Protocol Protocol
+	// FailoverEndpoints are other endpoints of the same daemon (VPN address, reverse proxy, etc...)
+	// tried in order when the ones before are unreachable. Each endpoint keeps its own session id.
+	// UnixSocket only applies to the primary endpoint: failover ones can use the unix scheme.
+	FailoverEndpoints []*url.URL
+	// FailoverCooldown is how long a failing endpoint is skipped before being tried again, allowing
+	// to fail back to a preferred endpoint once it recovers. Defaults to 30s.
+	FailoverCooldown time.Duration
+	// RateLimit, if set, limits the calls sent to the daemon (rate and concurrency), globally
+	// and/or per method class (reads and writes).
+	RateLimit *RateLimit
+	// CoalesceReads makes the concurrent identical read calls (torrent-get, session-get, session-stats,
+	// group-get and free-space with the same arguments) share a single HTTP round trip.
+	CoalesceReads bool
+	// SessionGetCacheTTL, if set, caches the session-get answers for this duration. The cache is
+	// dropped by any call modifying the daemon state made with this client.
+	SessionGetCacheTTL time.Duration
 }
 
-// New returns an initialized and ready to use Controller
+// New returns an initialized and ready to use Controller.
+// The endpoint can also point to a unix socket with the unix scheme, for example
+// "unix://user:password@/run/transmission/rpc.sock": the RPC path defaults to /transmission/rpc
+// and can be changed with the "path" query parameter.
 func New(transmissionRPCendpoint *url.URL, extra *Config) (c *Client, err error) {
 	// handle user input
 	if transmissionRPCendpoint == nil {
 		err = errors.New("please provide an Transmission RPC endpoint URL")
 		return
 	}
-	if extra != nil {
-		if extra.UserAgent == "" {
-			extra.UserAgent = defaultUserAgent
-		}
-		if extra.CustomClient == nil {
-			extra.CustomClient = cleanhttp.DefaultPooledClient()
-		}
-	} else {
-		extra = &Config{
-			UserAgent:    defaultUserAgent,
-			CustomClient: cleanhttp.DefaultPooledClient(),
+	if extra == nil {
+		extra = &Config{}
+	}
+	if extra.UserAgent == "" {
+		extra.UserAgent = defaultUserAgent
+	}
+	if extra.FailoverCooldown < 0 {
+		err = errors.New("failover cooldown can not be negative")
+		return
+	} else if extra.FailoverCooldown == 0 {
+		extra.FailoverCooldown = defaultFailoverCooldown
+	}
+	endpoints, err := newEndpoints(transmissionRPCendpoint, extra)
+	if err != nil {
+		return
+	}
+	if extra.SessionGetCacheTTL < 0 {
+		err = errors.New("session-get cache TTL can not be negative")
+		return
+	}
+	var limiter *rateLimiter
+	if extra.RateLimit != nil {
+		if limiter, err = newRateLimiter(*extra.RateLimit); err != nil {
+			err = fmt.Errorf("invalid rate limit: %w", err)
+			return
 		}
 	}
 	// Initialize & return ready to use client
 	c = &Client{
-		endpoint:     *transmissionRPCendpoint,
-		http:         extra.CustomClient,
-		userAgent:    extra.UserAgent,
-		tagGenerator: rand.New(newLockedRandomSource(time.Now().Unix())),
+		endpoints:        endpoints,
+		failoverCooldown: extra.FailoverCooldown,
+		userAgent:        extra.UserAgent,
+		auth:             extra.Authenticator,
+		tableFormat:      extra.TableFormat,
+		retryPolicy:      extra.RetryPolicy,
+		rateLimiter:      limiter,
+		reads:            newReadCoalescer(extra.CoalesceReads, extra.SessionGetCacheTTL),
+		interceptors:     append([]Interceptor(nil), extra.Interceptors...),
+		noFeatureGating:  extra.DisableFeatureGating,
+		compat:           extra.Compatibility,
+		protocol:         extra.Protocol,
+		tagGenerator:     rand.New(newLockedRandomSource(time.Now().Unix())),
+	}
+	return
+}
+
+// newHTTPClient returns a clean pooled HTTP client, dialing the unix socket if not empty.
+func newHTTPClient(socket string, tlsOptions *TLSConfig) (client *http.Client, err error) {
+	client = cleanhttp.DefaultPooledClient()
+	transport := client.Transport.(*http.Transport)
+	if socket != "" {
+		transport.Proxy = nil
+		transport.DialContext = unixSocketDialer(socket)
+	}
+	if tlsOptions != nil {
+		if transport.TLSClientConfig, err = tlsOptions.build(); err != nil {
+			err = fmt.Errorf("invalid TLS options: %w", err)
+			return
+		}
 	}
 	return
 }
@@ -59,31 +152,31 @@ func New(transmissionRPCendpoint *url.URL, extra *Config) (c *Client, err error)
 // It must be created with New().
 type Client struct {
 	// HTTP Client
-	endpoint  url.URL
-	http      *http.Client
-	userAgent string
+	endpoints        []*rpcEndpoint // primary first
+	failoverCooldown time.Duration
+	userAgent        string
+	auth             Authenticator
+	// Transmission RPC options
+	protocol     Protocol
+	tableFormat  bool
+	retryPolicy  *RetryPolicy
+	rateLimiter  *rateLimiter
+	reads        *readCoalescer
+	interceptors []Interceptor
+	// Server version discovery
+	noFeatureGating        bool
+	compat                 bool
+	serverVersion          atomic.Pointer[ServerVersion]
+	serverVersionDiscovery sync.Mutex
+	daemonRestarts         atomic.Uint64 // new daemon session ids received, see handleConflict()
 	// Transmission RPC protections
-	tagGenerator    *rand.Rand
-	sessionID       string
-	sessionIDAccess sync.RWMutex
+	tagGenerator *rand.Rand
 }
 
 func (c *Client) getRandomTag() int {
 	return c.tagGenerator.Int()
 }
 
-func (c *Client) getSessionID() string {
-	defer c.sessionIDAccess.RUnlock()
-	c.sessionIDAccess.RLock()
-	return c.sessionID
-}
-
-func (c *Client) updateSessionID(newID string) {
-	defer c.sessionIDAccess.Unlock()
-	c.sessionIDAccess.Lock()
-	c.sessionID = newID
-}
-
 // rand.NewSource is not thread-safe, so access should be serialized
 type lockedRandomSource struct {
 	mut sync.Mutex
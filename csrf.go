@@ -0,0 +1,98 @@
+package transmissionrpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+/*
+	CSRF protection
+	https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#231-csrf-protection
+*/
+
+// Connect eagerly performs the session id handshake with the daemon and discovers its versions
+// (see ServerVersion()). It is optional: the first call made with the client does it otherwise,
+// but it allows to check the daemon is reachable (and the credentials valid) before using it.
+func (c *Client) Connect(ctx context.Context) (err error) {
+	c.serverVersionDiscovery.Lock()
+	defer c.serverVersionDiscovery.Unlock()
+	if _, err = c.discoverServerVersion(ctx); err != nil {
+		err = fmt.Errorf("can't connect to the daemon: %w", err)
+	}
+	return
+}
+
+// sessionIDFor returns the session id to use for a new request. When the endpoint does not have
+// one yet, the first caller (leader) sends its request without it to get one from the 409 answer
+// while the concurrent callers wait for the outcome instead of all doing the same handshake.
+// The handshake ends as soon as a session id is stored, the leader must still call endHandshake()
+// once its request is done in case it did not get one.
+func (ep *rpcEndpoint) sessionIDFor(ctx context.Context) (sessionID string, leader bool, err error) {
+	for {
+		ep.access.Lock()
+		if ep.sessionID != "" {
+			sessionID = ep.sessionID
+			ep.access.Unlock()
+			return
+		}
+		if ep.handshake == nil {
+			ep.handshake = make(chan struct{})
+			ep.access.Unlock()
+			leader = true
+			return
+		}
+		handshake := ep.handshake
+		ep.access.Unlock()
+		select {
+		case <-handshake:
+			// leader done: use its session id or take the lead if it failed to get one
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
+}
+
+func (ep *rpcEndpoint) endHandshake() {
+	defer ep.access.Unlock()
+	ep.access.Lock()
+	ep.releaseHandshake()
+}
+
+// releaseHandshake wakes up the callers waiting for the running handshake, if any.
+// Caller must hold the access lock.
+func (ep *rpcEndpoint) releaseHandshake() {
+	if ep.handshake != nil {
+		close(ep.handshake)
+		ep.handshake = nil
+	}
+}
+
+// refreshSessionID stores the session id received within a 409 answer to a request sent with
+// used. If another caller already replaced used in the meantime, the stored one is kept (stale
+// is then true): concurrent 409s are coalesced into a single refresh.
+func (ep *rpcEndpoint) refreshSessionID(used, received string) (current string, stale, restarted bool) {
+	defer ep.access.Unlock()
+	ep.access.Lock()
+	if ep.sessionID != used {
+		return ep.sessionID, true, false
+	}
+	restarted = ep.sessionID != "" && ep.sessionID != received
+	ep.sessionID = received
+	// the waiting callers can send their requests without waiting for the leader one
+	ep.releaseHandshake()
+	return received, false, restarted
+}
+
+// handleConflict processes a 409 answer and returns the session id to retry with.
+func (c *Client) handleConflict(endpoint *rpcEndpoint, resp *http.Response, used string) (current string, stale bool) {
+	current, stale, restarted := endpoint.refreshSessionID(used, resp.Header.Get(csrfHeader))
+	if restarted {
+		// new daemon session: it may have been restarted with another version
+		c.daemonRestarts.Add(1)
+		c.serverVersion.Store(nil)
+		c.reads.invalidate()
+	}
+	return
+}
@@ -0,0 +1,157 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+// statusRecorder counts the answers of each HTTP status code going through it.
+type statusRecorder struct {
+	mutex    sync.Mutex
+	statuses map[int]int
+}
+
+func (sr *statusRecorder) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if resp, err = http.DefaultTransport.RoundTrip(req); err == nil {
+		sr.mutex.Lock()
+		if sr.statuses == nil {
+			sr.statuses = make(map[int]int)
+		}
+		sr.statuses[resp.StatusCode]++
+		sr.mutex.Unlock()
+	}
+	return
+}
+
+func (sr *statusRecorder) count(statusCode int) int {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	return sr.statuses[statusCode]
+}
+
+// concurrently runs fn n times concurrently and returns the first error.
+func concurrently(n int, fn func() error) (err error) {
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- fn()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for callErr := range errs {
+		if callErr != nil && err == nil {
+			err = callErr
+		}
+	}
+	return
+}
+
+func TestConcurrentHandshakesAreCoalesced(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.SetLatency(20 * time.Millisecond)
+	recorder := new(statusRecorder)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{CustomClient: &http.Client{Transport: recorder}})
+	err := concurrently(10, func() error {
+		_, err := client.SessionStats(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if conflicts := recorder.count(http.StatusConflict); conflicts != 1 {
+		t.Fatalf("expected a single 409 handshake, got %d", conflicts)
+	}
+	if successes := recorder.count(http.StatusOK); successes != 10 {
+		t.Fatalf("expected 10 successful requests, got %d", successes)
+	}
+}
+
+func TestHandshakeReleasedOnceSessionIDStored(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	// the requests are held by the daemon until all of them arrived: the waiters must not
+	// wait for the leader request to be answered to send theirs
+	const calls = 3
+	var (
+		mutex    sync.Mutex
+		arrived  int
+		timedOut bool
+	)
+	all := make(chan struct{})
+	daemon.OnRequest(func(method string, _ json.RawMessage) {
+		mutex.Lock()
+		if arrived++; arrived == calls {
+			close(all)
+		}
+		mutex.Unlock()
+		select {
+		case <-all:
+		case <-time.After(2 * time.Second):
+			mutex.Lock()
+			timedOut = true
+			mutex.Unlock()
+		}
+	})
+	err := concurrently(calls, func() error {
+		_, err := client.SessionStats(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if timedOut {
+		t.Fatal("the waiters were held until the leader request was answered")
+	}
+}
+
+func TestConcurrentSessionIDRefresh(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	// all the calls are rejected at once: none of them must be seen as a CSRF loop
+	daemon.SetLatency(20 * time.Millisecond)
+	daemon.RotateSessionID()
+	err := concurrently(10, func() error {
+		_, err := client.SessionStats(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	client := newTestClient(t, daemon, nil)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	if count := countRequests(daemon, "session-get"); count != 1 {
+		t.Fatalf("expected the server version to be discovered, got %d session-get", count)
+	}
+	version, err := client.ServerVersion(context.Background())
+	if err != nil || version.RPCVersion != 16 {
+		t.Fatalf("expected RPC v16, got %+v (err: %v)", version, err)
+	}
+	if count := countRequests(daemon, "session-get"); count != 1 {
+		t.Fatalf("expected the server version to be cached, got %d session-get", count)
+	}
+	// a closed daemon can not be connected to
+	daemon.Close()
+	if err = newTestClient(t, daemon, nil).Connect(context.Background()); err == nil {
+		t.Fatal("expected connecting to a closed daemon to fail")
+	}
+}
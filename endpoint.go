@@ -0,0 +1,206 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/*
+	Endpoints
+	A daemon can be reachable through several paths (LAN, VPN, reverse proxy, etc...): the client
+	fails over to the next endpoint when one is unreachable and fails back once it recovers.
+*/
+
+const (
+	defaultFailoverCooldown = 30 * time.Second
+)
+
+type rpcEndpoint struct {
+	name string // redacted configuration URL
+	url  url.URL
+	http *http.Client
+	// mutable state
+	access    sync.RWMutex
+	sessionID string
+	handshake chan struct{} // closed once the running session id handshake completes
+	downUntil time.Time
+	lastError error
+}
+
+// newEndpoints builds the client endpoints, the primary one first.
+func newEndpoints(primary *url.URL, extra *Config) (endpoints []*rpcEndpoint, err error) {
+	var (
+		sharedClient *http.Client
+		endpoint     *rpcEndpoint
+	)
+	if extra.CustomClient != nil {
+		if extra.TLS != nil {
+			err = errors.New("TLS options can't be used with a custom HTTP client: set its transport TLS config instead")
+			return
+		}
+		sharedClient = extra.CustomClient
+	}
+	endpoints = make([]*rpcEndpoint, 0, 1+len(extra.FailoverEndpoints))
+	for index, configured := range append([]*url.URL{primary}, extra.FailoverEndpoints...) {
+		if configured == nil {
+			err = fmt.Errorf("failover endpoint #%d is nil", index)
+			return
+		}
+		endpoint = &rpcEndpoint{
+			name: configured.Redacted(),
+			url:  *configured,
+		}
+		var socket string
+		if index == 0 {
+			socket = extra.UnixSocket
+		}
+		if endpoint.url.Scheme == unixScheme {
+			if socket, endpoint.url, err = unixSocketEndpoint(endpoint.url); err != nil {
+				return
+			}
+		}
+		switch {
+		case socket != "" && extra.CustomClient != nil:
+			err = errors.New("a unix socket can't be used with a custom HTTP client: set its transport dialer instead")
+			return
+		case socket != "":
+			// each socket needs its own dialer
+			if endpoint.http, err = newHTTPClient(socket, extra.TLS); err != nil {
+				return
+			}
+		default:
+			if sharedClient == nil {
+				if sharedClient, err = newHTTPClient("", extra.TLS); err != nil {
+					return
+				}
+			}
+			endpoint.http = sharedClient
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return
+}
+
+func (ep *rpcEndpoint) healthy(now time.Time) bool {
+	defer ep.access.RUnlock()
+	ep.access.RLock()
+	return !now.Before(ep.downUntil)
+}
+
+func (ep *rpcEndpoint) markDown(err error, cooldown time.Duration) {
+	defer ep.access.Unlock()
+	ep.access.Lock()
+	ep.downUntil = time.Now().Add(cooldown)
+	ep.lastError = err
+}
+
+func (ep *rpcEndpoint) markUp() {
+	defer ep.access.Unlock()
+	ep.access.Lock()
+	ep.downUntil = time.Time{}
+	ep.lastError = nil
+}
+
+// candidateEndpoints returns the endpoints to try for a call: the healthy ones in the configured
+// order first (allowing to fail back to a recovered endpoint), then the ones still cooling down
+// as a last resort.
+func (c *Client) candidateEndpoints() (candidates []*rpcEndpoint) {
+	if len(c.endpoints) == 1 {
+		return c.endpoints
+	}
+	now := time.Now()
+	candidates = make([]*rpcEndpoint, 0, len(c.endpoints))
+	var down []*rpcEndpoint
+	for _, endpoint := range c.endpoints {
+		if endpoint.healthy(now) {
+			candidates = append(candidates, endpoint)
+		} else {
+			down = append(down, endpoint)
+		}
+	}
+	return append(candidates, down...)
+}
+
+// shouldFailover returns true if the call can be sent again to another endpoint.
+// Like retries, non idempotent methods only fail over when the request was never received.
+func shouldFailover(method string, err error) bool {
+	var statusCode HTTPStatusCode
+	if errors.As(err, &statusCode) {
+		if !defaultRetryOnStatus(int(statusCode)) {
+			return false
+		}
+	} else if !defaultRetryOnError(err) {
+		return false
+	}
+	if nonIdempotentMethods[method] {
+		return requestNotSent(err)
+	}
+	return true
+}
+
+// EndpointStatus describes the health of one of the client endpoints.
+type EndpointStatus struct {
+	URL       string    // endpoint URL, password redacted
+	Healthy   bool      // false if the endpoint failed recently and is skipped until DownUntil
+	DownUntil time.Time // end of the cooldown, zero if healthy
+	LastError error     // error which marked the endpoint as down, nil if healthy
+}
+
+// Endpoints returns the status of the client endpoints, the primary one first.
+func (c *Client) Endpoints() (statuses []EndpointStatus) {
+	now := time.Now()
+	statuses = make([]EndpointStatus, len(c.endpoints))
+	for index, endpoint := range c.endpoints {
+		endpoint.access.RLock()
+		statuses[index] = EndpointStatus{
+			URL:       endpoint.name,
+			Healthy:   !now.Before(endpoint.downUntil),
+			LastError: endpoint.lastError,
+		}
+		if !statuses[index].Healthy {
+			statuses[index].DownUntil = endpoint.downUntil
+		}
+		endpoint.access.RUnlock()
+	}
+	return
+}
+
+/*
+	Served endpoint reporting
+*/
+
+type servedEndpointContextKey struct{}
+
+type servedEndpoint struct {
+	access sync.Mutex
+	name   string
+}
+
+// withServedEndpoint returns a context recording the endpoint serving the calls made with it,
+// reusing the recorder of ctx if any.
+func withServedEndpoint(ctx context.Context) (context.Context, *servedEndpoint) {
+	if recorder, ok := ctx.Value(servedEndpointContextKey{}).(*servedEndpoint); ok {
+		return ctx, recorder
+	}
+	recorder := new(servedEndpoint)
+	return context.WithValue(ctx, servedEndpointContextKey{}, recorder), recorder
+}
+
+func recordServedEndpoint(ctx context.Context, endpoint *rpcEndpoint) {
+	if recorder, ok := ctx.Value(servedEndpointContextKey{}).(*servedEndpoint); ok {
+		recorder.access.Lock()
+		recorder.name = endpoint.name
+		recorder.access.Unlock()
+	}
+}
+
+func (se *servedEndpoint) get() string {
+	defer se.access.Unlock()
+	se.access.Lock()
+	return se.name
+}
@@ -0,0 +1,130 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+// newSwitchableEndpoint exposes daemon through a proxy answering 503 while down is set.
+func newSwitchableEndpoint(t *testing.T, daemon *transmissionrpctest.Server, down *atomic.Bool) *url.URL {
+	t.Helper()
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: daemon.URL().Host})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	endpoint, _ := url.Parse(server.URL + transmissionrpctest.RPCPath)
+	return endpoint
+}
+
+func TestFailover(t *testing.T) {
+	daemon := newTestDaemon(t)
+	// unreachable primary
+	unreachable := newTestDaemon(t)
+	primary := unreachable.URL()
+	unreachable.Close()
+	client, err := transmissionrpc.New(primary, &transmissionrpc.Config{
+		FailoverEndpoints: []*url.URL{daemon.URL()},
+	})
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	if _, err = client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("expected the call to fail over: %v", err)
+	}
+	statuses := client.Endpoints()
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(statuses))
+	}
+	if statuses[0].Healthy || statuses[0].LastError == nil || statuses[0].DownUntil.IsZero() {
+		t.Fatalf("expected the primary endpoint to be down, got %+v", statuses[0])
+	}
+	if !statuses[1].Healthy || statuses[1].LastError != nil {
+		t.Fatalf("expected the failover endpoint to be healthy, got %+v", statuses[1])
+	}
+	// the primary endpoint is skipped while cooling down
+	if _, err = client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if count := countRequests(daemon, "session-stats"); count != 2 {
+		t.Fatalf("expected 2 calls served by the failover endpoint, got %d", count)
+	}
+}
+
+func TestFailoverFailBack(t *testing.T) {
+	daemon := newTestDaemon(t)
+	var primaryDown, secondaryDown atomic.Bool
+	primaryDown.Store(true)
+	primary := newSwitchableEndpoint(t, daemon, &primaryDown)
+	secondary := newSwitchableEndpoint(t, daemon, &secondaryDown)
+	var served []string
+	client, err := transmissionrpc.New(primary, &transmissionrpc.Config{
+		FailoverEndpoints: []*url.URL{secondary},
+		FailoverCooldown:  time.Minute,
+		Interceptors: []transmissionrpc.Interceptor{
+			transmissionrpc.Observe(func(ctx context.Context, info transmissionrpc.CallInfo) {
+				served = append(served, info.Endpoint)
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	ctx := context.Background()
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("expected the call to fail over: %v", err)
+	}
+	// recovered primary is used again once its cooldown is over
+	primaryDown.Store(false)
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	client.AgeFailoverCooldowns(time.Minute)
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(served) != 3 || served[0] != secondary.String() || served[1] != secondary.String() || served[2] != primary.String() {
+		t.Fatalf("expected the secondary endpoint to serve the calls until the primary cooldown is over, got %v", served)
+	}
+	if statuses := client.Endpoints(); !statuses[0].Healthy || !statuses[1].Healthy {
+		t.Fatalf("expected both endpoints to be healthy, got %+v", statuses)
+	}
+}
+
+func TestFailoverNonIdempotent(t *testing.T) {
+	daemon := newTestDaemon(t)
+	id := daemon.AddTorrent(map[string]interface{}{"name": "first"})
+	daemon.AddTorrent(map[string]interface{}{"name": "second"})
+	var primaryDown, secondaryDown atomic.Bool
+	client, err := transmissionrpc.New(newSwitchableEndpoint(t, daemon, &primaryDown), &transmissionrpc.Config{
+		FailoverEndpoints: []*url.URL{newSwitchableEndpoint(t, daemon, &secondaryDown)},
+	})
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	ctx := context.Background()
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	// the primary endpoint received the request: replaying it elsewhere could apply it twice
+	primaryDown.Store(true)
+	if err = client.QueueMoveUp(ctx, []int64{id}); err == nil {
+		t.Fatal("expected the non idempotent call not to fail over")
+	}
+	if count := countRequests(daemon, "queue-move-up"); count != 0 {
+		t.Fatalf("expected the call not to be replayed, got %d queue-move-up", count)
+	}
+}
@@ -0,0 +1,153 @@
+package transmissionrpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+	Errors
+	Can be inspected with errors.Is() and errors.As().
+*/
+
+var (
+	// ErrCSRFLoop is returned when the daemon rejects the CSRF session id it just provided.
+	ErrCSRFLoop = errors.New("CSRF token invalid 2 times in a row: stopping to avoid infinite loop")
+	// ErrMissingTag is returned when the answer payload does not contain the request tag.
+	ErrMissingTag = errors.New("http answer does not have a tag within it's payload")
+	// ErrTagMismatch is returned when the answer payload tag is not the one sent within the request.
+	ErrTagMismatch = errors.New("http request tag and answer payload tag do not match")
+	// ErrUnauthorized matches HTTPStatusCode errors with a 401 status: credentials are missing or invalid.
+	ErrUnauthorized = errors.New("unauthorized: missing or invalid credentials")
+	// ErrMisdirected matches HTTPStatusCode errors with a 421 status: the daemon rejected the
+	// host used to reach it (see its rpc-host-whitelist setting).
+	ErrMisdirected = errors.New("misdirected request: host rejected by the daemon whitelist")
+	// ErrUnsupportedByServer matches UnsupportedError errors: the daemon RPC version is too old
+	// for the requested method, field or argument.
+	ErrUnsupportedByServer = errors.New("not supported by the server")
+)
+
+// Sentinel errors matching the well known (non "success") results of the transmission daemon.
+var (
+	// ErrMethodNotRecognized indicates the daemon does not know the requested RPC method.
+	ErrMethodNotRecognized = errors.New("method name not recognized")
+	// ErrInvalidArgument indicates the daemon rejected one of the request arguments.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrInvalidTorrentFile indicates the daemon can't parse the torrent to add.
+	ErrInvalidTorrentFile = errors.New("invalid or corrupt torrent file")
+	// ErrDuplicateTorrent indicates the torrent to add is already known (old daemons only).
+	ErrDuplicateTorrent = errors.New("duplicate torrent")
+	// ErrTorrentNotFound indicates the daemon could not find the targeted torrent.
+	ErrTorrentNotFound = errors.New("torrent not found")
+)
+
+var knownResults = map[string]error{
+	"method name not recognized":             ErrMethodNotRecognized,
+	"invalid or corrupt torrent file":        ErrInvalidTorrentFile,
+	"duplicate torrent":                      ErrDuplicateTorrent,
+	"torrent not found":                      ErrTorrentNotFound,
+	"torrent-rename-path requires 1 torrent": ErrInvalidArgument,
+	"no filename or metainfo specified":      ErrInvalidArgument,
+	"no location":                            ErrInvalidArgument,
+}
+
+// resultError returns the sentinel error matching a daemon result, nil if unknown.
+func resultError(result string) error {
+	if err, found := knownResults[result]; found {
+		return err
+	}
+	if strings.Contains(strings.ToLower(result), "invalid argument") {
+		return ErrInvalidArgument
+	}
+	return nil
+}
+
+// RPCError is returned when the daemon answered the request but with a non "success" result.
+// Known results can be matched with errors.Is() against the sentinel errors (ErrInvalidArgument, etc...).
+type RPCError struct {
+	Method string // RPC method requested
+	Result string // result returned by the daemon
+	Tag    int    // tag of the request
+	Code   int    // error code (JSON-RPC protocol only)
+}
+
+func (re *RPCError) Error() string {
+	return fmt.Sprintf("http request ok but payload does not indicate success: %s", re.Result)
+}
+
+// Unwrap returns the sentinel error matching the result (or code), if known.
+func (re *RPCError) Unwrap() error {
+	switch re.Code {
+	case jsonRPCMethodNotFound:
+		return ErrMethodNotRecognized
+	case jsonRPCInvalidParams:
+		return ErrInvalidArgument
+	}
+	return resultError(re.Result)
+}
+
+// UnsupportedError is returned, before sending anything, when a method, field or argument
+// requires a RPC version newer than the daemon one. It matches ErrUnsupportedByServer.
+type UnsupportedError struct {
+	Feature         string // what is not supported
+	RequiredVersion int64  // RPC version needed
+	ServerVersion   int64  // RPC version of the daemon
+}
+
+func (ue *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s requires RPC version %d but the server RPC version is %d", ue.Feature, ue.RequiredVersion, ue.ServerVersion)
+}
+
+// Is allows to match ErrUnsupportedByServer.
+func (ue *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupportedByServer
+}
+
+// DaemonError is the failure of one daemon of a Fleet.
+type DaemonError struct {
+	Daemon string // name of the daemon within the fleet
+	Err    error  // error returned by its client
+}
+
+func (de *DaemonError) Error() string {
+	return fmt.Sprintf("daemon '%s': %v", de.Daemon, de.Err)
+}
+
+// Unwrap returns the client error.
+func (de *DaemonError) Unwrap() error {
+	return de.Err
+}
+
+// FleetError is returned by the Fleet methods when at least one daemon failed. The results of
+// the other daemons are still returned.
+type FleetError struct {
+	Failures []*DaemonError // sorted by daemon name
+}
+
+func (fe *FleetError) Error() string {
+	failures := make([]string, len(fe.Failures))
+	for index, failure := range fe.Failures {
+		failures[index] = failure.Error()
+	}
+	return fmt.Sprintf("%d daemon(s) failed: %s", len(fe.Failures), strings.Join(failures, "; "))
+}
+
+// Failed returns the names of the failed daemons.
+func (fe *FleetError) Failed() (names []string) {
+	names = make([]string, len(fe.Failures))
+	for index, failure := range fe.Failures {
+		names[index] = failure.Daemon
+	}
+	return
+}
+
+// Is allows to match target when all the daemons failed with it.
+func (fe *FleetError) Is(target error) bool {
+	for _, failure := range fe.Failures {
+		if !errors.Is(failure, target) {
+			return false
+		}
+	}
+	return len(fe.Failures) > 0
+}
@@ -0,0 +1,104 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+func TestRPCErrorSentinels(t *testing.T) {
+	for result, sentinel := range map[string]error{
+		"method name not recognized":             transmissionrpc.ErrMethodNotRecognized,
+		"invalid or corrupt torrent file":        transmissionrpc.ErrInvalidTorrentFile,
+		"duplicate torrent":                      transmissionrpc.ErrDuplicateTorrent,
+		"torrent not found":                      transmissionrpc.ErrTorrentNotFound,
+		"torrent-rename-path requires 1 torrent": transmissionrpc.ErrInvalidArgument,
+		"no location":                            transmissionrpc.ErrInvalidArgument,
+		"Invalid argument":                       transmissionrpc.ErrInvalidArgument,
+	} {
+		daemon := newTestDaemon(t)
+		daemon.FailNext("session-stats", transmissionrpctest.Failure{Result: result})
+		client := newTestClient(t, daemon, nil)
+		_, err := client.SessionStats(context.Background())
+		if !errors.Is(err, sentinel) {
+			t.Fatalf("result %q: expected %v, got %v", result, sentinel, err)
+		}
+		var rpcErr *transmissionrpc.RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Method != "session-stats" || rpcErr.Result != result {
+			t.Fatalf("result %q: expected a RPCError, got %v", result, err)
+		}
+	}
+	// unknown results only match the RPCError
+	daemon := newTestDaemon(t)
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{Result: "something went wrong"})
+	_, err := newTestClient(t, daemon, nil).SessionStats(context.Background())
+	var rpcErr *transmissionrpc.RPCError
+	if !errors.As(err, &rpcErr) || errors.Unwrap(rpcErr) != nil {
+		t.Fatalf("expected a RPCError without sentinel, got %v", err)
+	}
+}
+
+func TestRenamePathOfUnknownTorrent(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	err := client.TorrentRenamePath(context.Background(), 42, "old", "new")
+	if !errors.Is(err, transmissionrpc.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	if errors.Is(err, transmissionrpc.ErrTorrentNotFound) {
+		t.Fatalf("unexpected ErrTorrentNotFound match: %v", err)
+	}
+}
+
+func TestHTTPStatusCodeSentinels(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	for statusCode, sentinel := range map[int]error{
+		http.StatusUnauthorized:        transmissionrpc.ErrUnauthorized,
+		http.StatusMisdirectedRequest:  transmissionrpc.ErrMisdirected,
+		http.StatusInternalServerError: nil,
+	} {
+		daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: statusCode})
+		_, err := client.SessionStats(context.Background())
+		var httpErr transmissionrpc.HTTPStatusCode
+		if !errors.As(err, &httpErr) || int(httpErr) != statusCode {
+			t.Fatalf("status %d: expected a HTTPStatusCode, got %v", statusCode, err)
+		}
+		if sentinel != nil && !errors.Is(err, sentinel) {
+			t.Fatalf("status %d: expected %v, got %v", statusCode, sentinel, err)
+		}
+		if sentinel == nil && (errors.Is(err, transmissionrpc.ErrUnauthorized) || errors.Is(err, transmissionrpc.ErrMisdirected)) {
+			t.Fatalf("status %d: unexpected sentinel match: %v", statusCode, err)
+		}
+	}
+}
+
+func TestAnswerTagSentinels(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		answer   string
+		sentinel error
+	}{
+		"missing":  {answer: `{"arguments":{},"result":"success"}`, sentinel: transmissionrpc.ErrMissingTag},
+		"mismatch": {answer: `{"arguments":{},"result":"success","tag":-1}`, sentinel: transmissionrpc.ErrTagMismatch},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, testCase.answer)
+		}))
+		endpoint, _ := url.Parse(server.URL + "/transmission/rpc")
+		client, err := transmissionrpc.New(endpoint, nil)
+		if err != nil {
+			t.Fatalf("%s: can't create client: %v", name, err)
+		}
+		if _, err = client.SessionStats(context.Background()); !errors.Is(err, testCase.sentinel) {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.sentinel, err)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,40 @@
+package transmissionrpc
+
+import (
+	"context"
+	"time"
+)
+
+/*
+	Internals exposed to the transmissionrpc_test package
+*/
+
+// Poll executes a single watcher poll.
+func (w *Watcher) Poll(ctx context.Context, handler func(event TorrentEvent)) {
+	w.poll(ctx, handler)
+}
+
+// AgeLastPoll moves the last successful poll back in time.
+func (w *Watcher) AgeLastPoll(age time.Duration) {
+	w.lastPoll = w.lastPoll.Add(-age)
+}
+
+// AgeSessionGetCache moves the expiration of the cached session-get answers back in time.
+func (c *Client) AgeSessionGetCache(age time.Duration) {
+	c.reads.access.Lock()
+	defer c.reads.access.Unlock()
+	for _, answer := range c.reads.cache {
+		answer.expires = answer.expires.Add(-age)
+	}
+}
+
+// AgeFailoverCooldowns moves the end of the endpoints cooldowns back in time.
+func (c *Client) AgeFailoverCooldowns(age time.Duration) {
+	for _, endpoint := range c.endpoints {
+		endpoint.access.Lock()
+		if !endpoint.downUntil.IsZero() {
+			endpoint.downUntil = endpoint.downUntil.Add(-age)
+		}
+		endpoint.access.Unlock()
+	}
+}
@@ -0,0 +1,252 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hekmon/cunits/v2"
+)
+
+/*
+	Fleet
+	Fan-out of the most common requests over several named daemons.
+*/
+
+const (
+	defaultFleetConcurrency = 4
+)
+
+// FleetConfig contains the optional settings of a Fleet.
+type FleetConfig struct {
+	// Concurrency is the maximum number of daemons queried at the same time. Defaults to 4.
+	Concurrency int
+	// Timeout bounds each daemon request. Defaults to no timeout other than the caller context one.
+	Timeout time.Duration
+}
+
+// Fleet wraps several named clients and queries them concurrently. Failing daemons do not
+// prevent the results of the others from being returned: see FleetError.
+type Fleet struct {
+	names       []string // sorted
+	clients     map[string]*Client
+	concurrency int
+	timeout     time.Duration
+}
+
+// NewFleet returns a fleet over the given clients, indexed by their daemon name.
+// extra can be nil.
+func NewFleet(clients map[string]*Client, extra *FleetConfig) (f *Fleet, err error) {
+	if len(clients) == 0 {
+		err = errors.New("please provide at least one client")
+		return
+	}
+	f = &Fleet{
+		names:       make([]string, 0, len(clients)),
+		clients:     make(map[string]*Client, len(clients)),
+		concurrency: defaultFleetConcurrency,
+	}
+	for name, client := range clients {
+		if client == nil {
+			return nil, fmt.Errorf("client of daemon '%s' is nil", name)
+		}
+		f.names = append(f.names, name)
+		f.clients[name] = client
+	}
+	sort.Strings(f.names)
+	if extra != nil {
+		if extra.Concurrency > 0 {
+			f.concurrency = extra.Concurrency
+		}
+		if extra.Timeout < 0 {
+			return nil, errors.New("fleet timeout can not be negative")
+		}
+		f.timeout = extra.Timeout
+	}
+	return
+}
+
+// Daemons returns the names of the fleet daemons, sorted.
+func (f *Fleet) Daemons() (names []string) {
+	names = make([]string, len(f.names))
+	copy(names, f.names)
+	return
+}
+
+// Client returns the client of the named daemon, nil if unknown.
+func (f *Fleet) Client(name string) *Client {
+	return f.clients[name]
+}
+
+// fanOut calls fn for each daemon, at most concurrency at a time, and returns the daemons
+// errors (sorted by daemon name) as a *FleetError. fn must only write to per daemon storage.
+func (f *Fleet) fanOut(ctx context.Context, fn func(ctx context.Context, index int, name string, client *Client) error) (err error) {
+	var (
+		wg          sync.WaitGroup
+		semaphore   = make(chan struct{}, f.concurrency)
+		daemonsErrs = make([]error, len(f.names))
+	)
+	for index, name := range f.names {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			daemonsErrs[index] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(index int, name string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			daemonCtx := ctx
+			if f.timeout > 0 {
+				var cancel context.CancelFunc
+				daemonCtx, cancel = context.WithTimeout(ctx, f.timeout)
+				defer cancel()
+			}
+			daemonsErrs[index] = fn(daemonCtx, index, name, f.clients[name])
+		}(index, name)
+	}
+	wg.Wait()
+	var fleetErr FleetError
+	for index, daemonErr := range daemonsErrs {
+		if daemonErr != nil {
+			fleetErr.Failures = append(fleetErr.Failures, &DaemonError{
+				Daemon: f.names[index],
+				Err:    daemonErr,
+			})
+		}
+	}
+	if len(fleetErr.Failures) > 0 {
+		err = &fleetErr
+	}
+	return
+}
+
+/*
+	Torrents
+*/
+
+// FleetTorrent is a torrent tagged with the name of the daemon it belongs to.
+type FleetTorrent struct {
+	Daemon  string
+	Torrent Torrent
+}
+
+// TorrentGet returns the given fields (mandatory) of the torrents of every daemon, or only the
+// ones matching hashes (optionnal) as ids are specific to each daemon. Torrents are grouped by
+// daemon, sorted by name. On partial failure, the torrents of the healthy daemons are returned
+// along with a *FleetError.
+func (f *Fleet) TorrentGet(ctx context.Context, fields []string, hashes []string) (torrents []FleetTorrent, err error) {
+	daemonsTorrents := make([][]Torrent, len(f.names))
+	err = f.fanOut(ctx, func(ctx context.Context, index int, _ string, client *Client) (err error) {
+		daemonsTorrents[index], err = client.TorrentGetHashes(ctx, fields, hashes)
+		return
+	})
+	var total int
+	for _, daemonTorrents := range daemonsTorrents {
+		total += len(daemonTorrents)
+	}
+	torrents = make([]FleetTorrent, 0, total)
+	for index, daemonTorrents := range daemonsTorrents {
+		for _, torrent := range daemonTorrents {
+			torrents = append(torrents, FleetTorrent{
+				Daemon:  f.names[index],
+				Torrent: torrent,
+			})
+		}
+	}
+	return
+}
+
+// TorrentStartHashes starts the torrent(s) which hash is in the provided slice on every daemon.
+// Can be one, can be several, can be all (if slice is empty or nil).
+func (f *Fleet) TorrentStartHashes(ctx context.Context, hashes []string) (err error) {
+	return f.fanOut(ctx, func(ctx context.Context, _ int, _ string, client *Client) error {
+		return client.TorrentStartHashes(ctx, hashes)
+	})
+}
+
+// TorrentStartNowHashes starts (now) the torrent(s) which hash is in the provided slice on every daemon.
+// Can be one, can be several, can be all (if slice is empty or nil).
+func (f *Fleet) TorrentStartNowHashes(ctx context.Context, hashes []string) (err error) {
+	return f.fanOut(ctx, func(ctx context.Context, _ int, _ string, client *Client) error {
+		return client.TorrentStartNowHashes(ctx, hashes)
+	})
+}
+
+// TorrentStopHashes stops the torrent(s) which hash is in the provided slice on every daemon.
+// Can be one, can be several, can be all (if slice is empty or nil).
+func (f *Fleet) TorrentStopHashes(ctx context.Context, hashes []string) (err error) {
+	return f.fanOut(ctx, func(ctx context.Context, _ int, _ string, client *Client) error {
+		return client.TorrentStopHashes(ctx, hashes)
+	})
+}
+
+/*
+	Session
+*/
+
+// FleetSessionStats are the session stats of a daemon.
+type FleetSessionStats struct {
+	Daemon string
+	Stats  SessionStats
+}
+
+// SessionStats returns the session stats of every daemon, sorted by daemon name. On partial
+// failure, the stats of the healthy daemons are returned along with a *FleetError.
+func (f *Fleet) SessionStats(ctx context.Context) (stats []FleetSessionStats, err error) {
+	daemonsStats := make([]*SessionStats, len(f.names))
+	err = f.fanOut(ctx, func(ctx context.Context, index int, _ string, client *Client) error {
+		daemonStats, err := client.SessionStats(ctx)
+		if err == nil {
+			daemonsStats[index] = &daemonStats
+		}
+		return err
+	})
+	stats = make([]FleetSessionStats, 0, len(f.names))
+	for index, daemonStats := range daemonsStats {
+		if daemonStats != nil {
+			stats = append(stats, FleetSessionStats{
+				Daemon: f.names[index],
+				Stats:  *daemonStats,
+			})
+		}
+	}
+	return
+}
+
+// FleetFreeSpace is the space available on a daemon.
+type FleetFreeSpace struct {
+	Daemon    string
+	FreeSpace cunits.Bits
+	TotalSize cunits.Bits
+}
+
+// FreeSpace returns the space available in path on every daemon, sorted by daemon name. On partial
+// failure, the values of the healthy daemons are returned along with a *FleetError.
+func (f *Fleet) FreeSpace(ctx context.Context, path string) (spaces []FleetFreeSpace, err error) {
+	daemonsSpaces := make([]*FleetFreeSpace, len(f.names))
+	err = f.fanOut(ctx, func(ctx context.Context, index int, name string, client *Client) error {
+		freeSpace, totalSize, err := client.FreeSpace(ctx, path)
+		if err == nil {
+			daemonsSpaces[index] = &FleetFreeSpace{
+				Daemon:    name,
+				FreeSpace: freeSpace,
+				TotalSize: totalSize,
+			}
+		}
+		return err
+	})
+	spaces = make([]FleetFreeSpace, 0, len(f.names))
+	for _, daemonSpace := range daemonsSpaces {
+		if daemonSpace != nil {
+			spaces = append(spaces, *daemonSpace)
+		}
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+func TestFleet(t *testing.T) {
+	alpha, beta := newTestDaemon(t), newTestDaemon(t)
+	alpha.AddTorrent(map[string]interface{}{"name": "shared", "hashString": "aa00000000000000000000000000000000000000"})
+	beta.AddTorrent(map[string]interface{}{"name": "other", "hashString": "bb00000000000000000000000000000000000000"})
+	beta.AddTorrent(map[string]interface{}{"name": "shared", "hashString": "aa00000000000000000000000000000000000000"})
+	alpha.SetFreeSpace("/data", 10, 100)
+	beta.SetFreeSpace("/data", 20, 100)
+	fleet, err := transmissionrpc.NewFleet(map[string]*transmissionrpc.Client{
+		"beta":  newTestClient(t, beta, nil),
+		"alpha": newTestClient(t, alpha, nil),
+	}, nil)
+	if err != nil {
+		t.Fatalf("can't create fleet: %v", err)
+	}
+	if daemons := fleet.Daemons(); !reflect.DeepEqual(daemons, []string{"alpha", "beta"}) {
+		t.Fatalf("unexpected daemons: %v", daemons)
+	}
+	ctx := context.Background()
+	// torrents grouped by daemon
+	torrents, err := fleet.TorrentGet(ctx, []string{"name"}, nil)
+	if err != nil {
+		t.Fatalf("fleet torrent-get failed: %v", err)
+	}
+	var got []string
+	for _, torrent := range torrents {
+		got = append(got, torrent.Daemon+"/"+*torrent.Torrent.Name)
+	}
+	if !reflect.DeepEqual(got, []string{"alpha/shared", "beta/other", "beta/shared"}) {
+		t.Fatalf("unexpected fleet torrents: %v", got)
+	}
+	// hashes are shared between daemons
+	if err = fleet.TorrentStartHashes(ctx, []string{"aa00000000000000000000000000000000000000"}); err != nil {
+		t.Fatalf("fleet start failed: %v", err)
+	}
+	for _, daemon := range []*transmissionrpctest.Server{alpha, beta} {
+		for _, id := range daemon.TorrentIDs() {
+			fields, _ := daemon.Torrent(id)
+			started := fields["status"] == int(transmissionrpc.TorrentStatusDownload)
+			if started != (fields["name"] == "shared") {
+				t.Fatalf("unexpected status %v for torrent '%v'", fields["status"], fields["name"])
+			}
+		}
+	}
+	// per daemon values
+	spaces, err := fleet.FreeSpace(ctx, "/data")
+	if err != nil {
+		t.Fatalf("fleet free-space failed: %v", err)
+	}
+	if len(spaces) != 2 || spaces[0].Daemon != "alpha" || spaces[0].FreeSpace.Byte() != 10 ||
+		spaces[1].Daemon != "beta" || spaces[1].FreeSpace.Byte() != 20 {
+		t.Fatalf("unexpected fleet free spaces: %+v", spaces)
+	}
+}
+
+func TestFleetPartialFailure(t *testing.T) {
+	healthy, failing := newTestDaemon(t), newTestDaemon(t)
+	fleet, err := transmissionrpc.NewFleet(map[string]*transmissionrpc.Client{
+		"healthy": newTestClient(t, healthy, nil),
+		"failing": newTestClient(t, failing, nil),
+	}, nil)
+	if err != nil {
+		t.Fatalf("can't create fleet: %v", err)
+	}
+	failing.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusBadGateway})
+	stats, err := fleet.SessionStats(context.Background())
+	if len(stats) != 1 || stats[0].Daemon != "healthy" {
+		t.Fatalf("expected the healthy daemon stats, got %+v", stats)
+	}
+	var fleetErr *transmissionrpc.FleetError
+	if !errors.As(err, &fleetErr) || !reflect.DeepEqual(fleetErr.Failed(), []string{"failing"}) {
+		t.Fatalf("expected a fleet error for the failing daemon, got %v", err)
+	}
+	if !errors.Is(err, transmissionrpc.HTTPStatusCode(http.StatusBadGateway)) {
+		t.Fatalf("expected the daemon error to be matched, got %v", err)
+	}
+}
+
+func TestFleetTimeout(t *testing.T) {
+	fast, slow := newTestDaemon(t), newTestDaemon(t)
+	slow.SetLatency(500 * time.Millisecond)
+	fleet, err := transmissionrpc.NewFleet(map[string]*transmissionrpc.Client{
+		"fast": newTestClient(t, fast, nil),
+		"slow": newTestClient(t, slow, nil),
+	}, &transmissionrpc.FleetConfig{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("can't create fleet: %v", err)
+	}
+	start := time.Now()
+	stats, err := fleet.SessionStats(context.Background())
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Fatalf("expected the slow daemon to be timed out, took %v", elapsed)
+	}
+	if len(stats) != 1 || stats[0].Daemon != "fast" {
+		t.Fatalf("expected the fast daemon stats, got %+v", stats)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got %v", err)
+	}
+}
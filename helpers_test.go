@@ -0,0 +1,238 @@
+package transmissionrpc_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+/*
+	Test helpers
+*/
+
+// newTestDaemon starts a fake daemon stopped at the end of the test.
+func newTestDaemon(t *testing.T) *transmissionrpctest.Server {
+	t.Helper()
+	daemon := transmissionrpctest.NewServer()
+	t.Cleanup(daemon.Close)
+	return daemon
+}
+
+// newTestClient returns a client of daemon, failing the test on error.
+func newTestClient(t *testing.T, daemon *transmissionrpctest.Server, config *transmissionrpc.Config) *transmissionrpc.Client {
+	t.Helper()
+	client, err := daemon.Client(config)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	return client
+}
+
+// countRequests returns the number of requests of method received by daemon.
+func countRequests(daemon *transmissionrpctest.Server, method string) (count int) {
+	for _, request := range daemon.Requests() {
+		if request.Method == method {
+			count++
+		}
+	}
+	return
+}
+
+const stubSessionID = "stub-session-id"
+
+// stubDaemon is a minimal legacy daemon: it handles the session id handshake and the tag echo,
+// answering each RPC method with its registered handler.
+type stubDaemon struct {
+	server    *httptest.Server
+	mutex     sync.Mutex
+	sessionID string
+	handlers  map[string]stubHandler
+	requests  []stubRequest
+}
+
+// stubHandler answers a RPC method: the returned error is used as the answer result.
+type stubHandler func(arguments json.RawMessage) (result interface{}, err error)
+
+type stubRequest struct {
+	Method    string
+	Arguments json.RawMessage
+}
+
+// newStubDaemon starts a stub daemon stopped at the end of the test. It answers session-get
+// with a RPC v17 daemon versions.
+func newStubDaemon(t *testing.T) *stubDaemon {
+	t.Helper()
+	daemon := &stubDaemon{
+		sessionID: stubSessionID,
+		handlers: map[string]stubHandler{
+			"session-get": func(json.RawMessage) (interface{}, error) {
+				return map[string]interface{}{
+					"rpc-version":         17,
+					"rpc-version-minimum": 14,
+					"version":             "4.0.3",
+				}, nil
+			},
+		},
+	}
+	daemon.server = httptest.NewServer(http.HandlerFunc(daemon.serve))
+	t.Cleanup(daemon.server.Close)
+	return daemon
+}
+
+func (sd *stubDaemon) serve(w http.ResponseWriter, r *http.Request) {
+	sd.mutex.Lock()
+	sessionID := sd.sessionID
+	sd.mutex.Unlock()
+	if r.Header.Get("X-Transmission-Session-Id") != sessionID {
+		w.Header().Set("X-Transmission-Session-Id", sessionID)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	var request struct {
+		Method    string          `json:"method"`
+		Arguments json.RawMessage `json:"arguments"`
+		Tag       int             `json:"tag"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sd.mutex.Lock()
+	sd.requests = append(sd.requests, stubRequest{
+		Method:    request.Method,
+		Arguments: request.Arguments,
+	})
+	handler, found := sd.handlers[request.Method]
+	sd.mutex.Unlock()
+	answer := map[string]interface{}{
+		"result": "success",
+		"tag":    request.Tag,
+	}
+	if !found {
+		answer["result"] = "method name not recognized"
+	} else if arguments, err := handler(request.Arguments); err != nil {
+		answer["result"] = err.Error()
+	} else if arguments != nil {
+		answer["arguments"] = arguments
+	}
+	_ = json.NewEncoder(w).Encode(answer)
+}
+
+// rotateSessionID changes the session id, as a daemon restart does.
+func (sd *stubDaemon) rotateSessionID() {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	sd.sessionID += "-restarted"
+}
+
+// handle registers the handler of method.
+func (sd *stubDaemon) handle(method string, handler stubHandler) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	sd.handlers[method] = handler
+}
+
+// handleTorrents makes torrent-get answer the given torrents with the requested fields, using the
+// requested format. Torrents are selected by their ids or hashes, all of them by default.
+func (sd *stubDaemon) handleTorrents(torrents ...map[string]interface{}) {
+	sd.handle("torrent-get", func(arguments json.RawMessage) (interface{}, error) {
+		var args struct {
+			Fields []string      `json:"fields"`
+			IDs    []interface{} `json:"ids"`
+			Format string        `json:"format"`
+		}
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, err
+		}
+		var selected []map[string]interface{}
+		for _, torrent := range torrents {
+			if len(args.IDs) == 0 {
+				selected = append(selected, torrent)
+				continue
+			}
+			for _, ref := range args.IDs {
+				if ref == torrent["hashString"] || ref == toFloat64(torrent["id"]) {
+					selected = append(selected, torrent)
+				}
+			}
+		}
+		if args.Format == "table" {
+			table := []interface{}{args.Fields}
+			for _, torrent := range selected {
+				row := make([]interface{}, len(args.Fields))
+				for index, field := range args.Fields {
+					row[index] = torrent[field]
+				}
+				table = append(table, row)
+			}
+			return map[string]interface{}{"torrents": table}, nil
+		}
+		objects := make([]map[string]interface{}, 0, len(selected))
+		for _, torrent := range selected {
+			object := make(map[string]interface{}, len(args.Fields))
+			for _, field := range args.Fields {
+				if value, found := torrent[field]; found {
+					object[field] = value
+				}
+			}
+			objects = append(objects, object)
+		}
+		return map[string]interface{}{"torrents": objects}, nil
+	})
+}
+
+// client returns a client of the stub daemon, failing the test on error.
+func (sd *stubDaemon) client(t *testing.T, config *transmissionrpc.Config) *transmissionrpc.Client {
+	t.Helper()
+	endpoint, _ := url.Parse(sd.server.URL + "/transmission/rpc")
+	client, err := transmissionrpc.New(endpoint, config)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	return client
+}
+
+// lastArguments returns the raw arguments of the last request of method, failing the test if none.
+func (sd *stubDaemon) lastArguments(t *testing.T, method string) string {
+	t.Helper()
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	for index := len(sd.requests) - 1; index >= 0; index-- {
+		if sd.requests[index].Method == method {
+			return string(sd.requests[index].Arguments)
+		}
+	}
+	t.Fatalf("no '%s' request received", method)
+	return ""
+}
+
+// countRequests returns the number of requests of method received by the stub daemon.
+func (sd *stubDaemon) countRequests(method string) (count int) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	for _, request := range sd.requests {
+		if request.Method == method {
+			count++
+		}
+	}
+	return
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,82 @@
+package transmissionrpc
+
+import (
+	"context"
+	"time"
+)
+
+/*
+	Interceptors
+	Allows to compose cross-cutting concerns (logging, metrics, tracing, headers, etc...) around every RPC call.
+*/
+
+// Invoker executes a RPC call: arguments are sent with the method and the answer arguments are
+// decoded into result. result is nil for streamed calls (see TorrentGetIterator()).
+type Invoker func(ctx context.Context, method string, arguments, result interface{}) error
+
+// Interceptor wraps a RPC call. It must call next to continue the chain (it may alter the context,
+// method or arguments before) and can inspect result and the returned error afterwards.
+// Retries (see RetryPolicy) happen within next: an interceptor sees one call per client method call.
+type Interceptor func(ctx context.Context, method string, arguments, result interface{}, next Invoker) error
+
+// CallInfo describes a completed RPC call, see Observe().
+type CallInfo struct {
+	Method    string
+	Arguments interface{}
+	Result    interface{}
+	Duration  time.Duration
+	Err       error
+	Endpoint  string // endpoint which served the call (password redacted), empty if none did
+	RequestID string // see the RequestID() call option
+}
+
+// Observe returns an interceptor calling fn once each RPC call has completed. Handy for logging and metrics.
+func Observe(fn func(ctx context.Context, info CallInfo)) Interceptor {
+	return func(ctx context.Context, method string, arguments, result interface{}, next Invoker) (err error) {
+		ctx, served := withServedEndpoint(ctx)
+		start := time.Now()
+		err = next(ctx, method, arguments, result)
+		fn(ctx, CallInfo{
+			Method:    method,
+			Arguments: arguments,
+			Result:    result,
+			Duration:  time.Since(start),
+			Err:       err,
+			Endpoint:  served.get(),
+			RequestID: RequestIDFromContext(ctx),
+		})
+		return
+	}
+}
+
+// invoke runs final through the client interceptors, the first one registered being the outermost.
+func (c *Client) invoke(ctx context.Context, method string, arguments, result interface{}, final Invoker) error {
+	if len(c.interceptors) == 0 {
+		return final(ctx, method, arguments, result)
+	}
+	return chainInterceptors(c.interceptors, final)(ctx, method, arguments, result)
+}
+
+func chainInterceptors(interceptors []Interceptor, final Invoker) (chained Invoker) {
+	chained = final
+	for index := len(interceptors) - 1; index >= 0; index-- {
+		interceptor, next := interceptors[index], chained
+		chained = func(ctx context.Context, method string, arguments, result interface{}) error {
+			return interceptor(ctx, method, arguments, result, next)
+		}
+	}
+	return
+}
+
+/*
+	Headers injection
+*/
+
+// WithHeader returns a context adding a HTTP header to the requests made with it.
+// It can be used by interceptors (tracing propagation, etc...) or directly when calling a method.
+// Unlike the CallHeader() option, it does not replace the existing values.
+func WithHeader(ctx context.Context, key, value string) context.Context {
+	return WithCallOptions(ctx, func(opts *callOptions) {
+		opts.headers = append(opts.headers, headerOption{key: key, value: value})
+	})
+}
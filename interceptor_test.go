@@ -0,0 +1,106 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+func TestInterceptorsOrder(t *testing.T) {
+	daemon := newTestDaemon(t)
+	var calls []string
+	tracing := func(name string) transmissionrpc.Interceptor {
+		return func(ctx context.Context, method string, arguments, result interface{}, next transmissionrpc.Invoker) error {
+			calls = append(calls, name+" before "+method)
+			err := next(ctx, method, arguments, result)
+			calls = append(calls, name+" after "+method)
+			return err
+		}
+	}
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		Interceptors: []transmissionrpc.Interceptor{tracing("outer"), tracing("inner")},
+	})
+	if _, err := client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	expected := []string{
+		"outer before session-stats",
+		"inner before session-stats",
+		"inner after session-stats",
+		"outer after session-stats",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("unexpected interceptors calls: %v", calls)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	daemon := newTestDaemon(t)
+	denied := errors.New("denied")
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		Interceptors: []transmissionrpc.Interceptor{
+			func(ctx context.Context, method string, arguments, result interface{}, next transmissionrpc.Invoker) error {
+				if method == "session-close" {
+					return denied
+				}
+				return next(ctx, method, arguments, result)
+			},
+		},
+	})
+	if err := client.SessionClose(context.Background()); !errors.Is(err, denied) {
+		t.Fatalf("expected the interceptor error, got %v", err)
+	}
+	if count := countRequests(daemon, "session-close"); count != 0 {
+		t.Fatalf("expected the call not to be sent, got %d session-close", count)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	daemon := newTestDaemon(t)
+	var infos []transmissionrpc.CallInfo
+	endpoint := daemon.URL()
+	endpoint.User = url.UserPassword("admin", "secret")
+	client, err := transmissionrpc.New(endpoint, &transmissionrpc.Config{
+		RetryPolicy: &transmissionrpc.RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+		Interceptors: []transmissionrpc.Interceptor{
+			transmissionrpc.Observe(func(ctx context.Context, info transmissionrpc.CallInfo) {
+				infos = append(infos, info)
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	// retries happen within a single observed call
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if _, err = client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 observed call, got %d", len(infos))
+	}
+	info := infos[0]
+	if info.Method != "session-stats" || info.Err != nil || info.Result == nil || info.Duration <= 0 ||
+		info.Endpoint == "" || strings.Contains(info.Endpoint, "secret") {
+		t.Fatalf("unexpected call info: %+v", info)
+	}
+	// failures are observed too
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{Result: "boom"})
+	if _, err = client.SessionStats(context.Background()); err == nil {
+		t.Fatal("expected the call to fail")
+	}
+	if info = infos[len(infos)-1]; info.Err == nil || !strings.Contains(info.Err.Error(), "boom") {
+		t.Fatalf("unexpected call info: %+v", info)
+	}
+}
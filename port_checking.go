@@ -10,6 +10,16 @@ import (
     https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#44-port-checking
 */
 
+// IPProtocol selects the IP protocol used to test the peer port.
+type IPProtocol string
+
+const (
+	// IPv4 tests the peer port over IPv4
+	IPv4 IPProtocol = "ipv4"
+	// IPv6 tests the peer port over IPv6
+	IPv6 IPProtocol = "ipv6"
+)
+
 // PortTest allows tests to see if your incoming peer port is accessible from the outside world.
 func (c *Client) PortTest(ctx context.Context) (open bool, err error) {
 	var result portTestAnswer
@@ -22,6 +32,33 @@ func (c *Client) PortTest(ctx context.Context) (open bool, err error) {
 	return
 }
 
+// PortTestIP allows tests to see if your incoming peer port is accessible from the outside world
+// over a specific IP protocol (RPC v18).
+func (c *Client) PortTestIP(ctx context.Context, ipProtocol IPProtocol) (open bool, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	if err = c.requireRPCVersion(ctx, "port-test argument 'ip_protocol'", portTestIPProtocolRPCVersion); err != nil {
+		return
+	}
+	var result portTestAnswer
+	// Send request
+	if err = c.rpcCall(ctx, "port-test", portTestParams{IPProtocol: ipProtocol}, &result); err != nil {
+		err = fmt.Errorf("'port-test' rpc method failed: %w", err)
+		return
+	}
+	if result.IPProtocol != "" && result.IPProtocol != ipProtocol {
+		err = fmt.Errorf("'port-test' rpc method failed: asked for %s but the server tested %s", ipProtocol, result.IPProtocol)
+		return
+	}
+	open = result.PortOpen
+	return
+}
+
+type portTestParams struct {
+	IPProtocol IPProtocol `json:"ip_protocol"`
+}
+
 type portTestAnswer struct {
-	PortOpen bool `json:"port-is-open"`
+	PortOpen   bool       `json:"port-is-open"`
+	IPProtocol IPProtocol `json:"ip_protocol"` // RPC v18
 }
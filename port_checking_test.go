@@ -0,0 +1,47 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestPortTestIP(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, nil)
+	ctx := context.Background()
+	// RPC v18 argument
+	if _, err := client.PortTestIP(ctx, transmissionrpc.IPv6); !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer on RPC v17, got %v", err)
+	}
+	if count := countRequests(daemon, "port-test"); count != 0 {
+		t.Fatalf("unsupported call must not be sent, got %d port-test", count)
+	}
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 18})
+	client = newTestClient(t, daemon, nil)
+	daemon.SetPortOpen(true)
+	open, err := client.PortTestIP(ctx, transmissionrpc.IPv6)
+	if err != nil || !open {
+		t.Fatalf("expected the port to be open, got %v (err: %v)", open, err)
+	}
+	requests := daemon.Requests()
+	if args := string(requests[len(requests)-1].Arguments); args != `{"ip_protocol":"ipv6"}` {
+		t.Fatalf("unexpected port-test arguments: %s", args)
+	}
+}
+
+func TestPortTestIPMismatch(t *testing.T) {
+	daemon := newJSONRPCDaemon(t, map[string]string{
+		"session_get": jsonRPCSessionGetResult,
+		"port_test":   `{"port_is_open":true,"ip_protocol":"ipv4"}`,
+	})
+	client := daemon.client(t, transmissionrpc.Config{})
+	if _, err := client.PortTestIP(context.Background(), transmissionrpc.IPv6); err == nil {
+		t.Fatal("expected an answer for another IP protocol to be rejected")
+	}
+	if params := daemon.lastParams(t, "port_test"); params["ip_protocol"] != "ipv6" {
+		t.Fatalf("unexpected port_test params: %v", params)
+	}
+}
@@ -0,0 +1,451 @@
+package transmissionrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+/*
+	Wire protocols
+	Transmission 4.1 (RPC v18) speaks JSON-RPC 2.0 with snake_case method, argument and field names,
+	the legacy format (and names) being deprecated. The library types keep the legacy names: they are
+	translated on the fly when the JSON-RPC protocol is used.
+	https://github.com/transmission/transmission/blob/main/docs/rpc-spec.md
+*/
+
+// Protocol is the wire format used to talk to the daemon.
+type Protocol int
+
+const (
+	// ProtocolLegacy is the historical format: {"method", "arguments", "tag"} with kebab/camel case names.
+	ProtocolLegacy Protocol = iota
+	// ProtocolJSONRPC is the JSON-RPC 2.0 format with snake_case names of transmission 4.1 and newer.
+	ProtocolJSONRPC
+	// ProtocolAuto starts with the legacy format to discover the daemon version and switches to
+	// JSON-RPC 2.0 if the daemon supports it.
+	ProtocolAuto
+)
+
+const (
+	jsonRPCVersion    = "2.0"
+	jsonRPCRPCVersion = 18
+	// JSON-RPC 2.0 reserved error codes
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolLegacy:
+		return "legacy"
+	case ProtocolJSONRPC:
+		return "JSON-RPC 2.0"
+	case ProtocolAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("unknown protocol %d", int(p))
+	}
+}
+
+// wireProtocol encodes requests and decodes answers for a given wire format.
+type wireProtocol interface {
+	encodeRequest(method string, arguments interface{}, tag int) (payload []byte, err error)
+	decodeAnswer(method string, body io.Reader, result interface{}, tag int) (err error)
+}
+
+// wireProtocol returns the wire protocol to use for the next request.
+func (c *Client) wireProtocol() wireProtocol {
+	switch c.protocol {
+	case ProtocolJSONRPC:
+		return jsonRPCProtocol{}
+	case ProtocolAuto:
+		if version := c.serverVersion.Load(); version != nil && version.Supports(jsonRPCRPCVersion) {
+			return jsonRPCProtocol{}
+		}
+	}
+	return legacyProtocol{}
+}
+
+// discoverProtocol discovers the daemon version (using the legacy protocol) if the protocol must
+// be auto selected and the version is not known yet. Failures are ignored: the legacy protocol
+// is still understood by the JSON-RPC daemons.
+func (c *Client) discoverProtocol(ctx context.Context, method string) {
+	if c.protocol != ProtocolAuto || method == "session-get" || c.serverVersion.Load() != nil {
+		return
+	}
+	_, _ = c.ServerVersion(ctx)
+}
+
+/*
+	Legacy
+*/
+
+type legacyProtocol struct{}
+
+func (legacyProtocol) encodeRequest(method string, arguments interface{}, tag int) (payload []byte, err error) {
+	return json.Marshal(requestPayload{
+		Method:    method,
+		Arguments: arguments,
+		Tag:       tag,
+	})
+}
+
+func (legacyProtocol) decodeAnswer(method string, body io.Reader, result interface{}, tag int) (err error) {
+	answer := answerPayload{
+		Arguments: result,
+	}
+	if err = json.NewDecoder(body).Decode(&answer); err != nil {
+		return fmt.Errorf("can't unmarshal request answer body: %w", err)
+	}
+	return checkAnswer(method, answer.Result, answer.Tag, tag)
+}
+
+/*
+	JSON-RPC 2.0
+*/
+
+type jsonRPCProtocol struct{}
+
+type jsonRPCRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      int         `json:"id"`
+}
+
+type jsonRPCAnswer struct {
+	Result json.RawMessage `json:"result"`
+	Error  *jsonRPCError   `json:"error"`
+	ID     *int            `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		ErrorString string `json:"error_string"`
+	} `json:"data"`
+}
+
+func (jsonRPCProtocol) encodeRequest(method string, arguments interface{}, tag int) (payload []byte, err error) {
+	request := jsonRPCRequest{
+		JSONRPC: jsonRPCVersion,
+		Method:  toSnakeCase(method),
+		ID:      tag,
+	}
+	if arguments != nil {
+		if request.Params, err = translateRequest(arguments); err != nil {
+			return nil, fmt.Errorf("can't translate arguments to snake case: %w", err)
+		}
+	}
+	return json.Marshal(request)
+}
+
+func (jsonRPCProtocol) decodeAnswer(method string, body io.Reader, result interface{}, tag int) (err error) {
+	var answer jsonRPCAnswer
+	if err = json.NewDecoder(body).Decode(&answer); err != nil {
+		return fmt.Errorf("can't unmarshal request answer body: %w", err)
+	}
+	if answer.ID == nil {
+		return ErrMissingTag
+	}
+	if *answer.ID != tag {
+		return ErrTagMismatch
+	}
+	if answer.Error != nil {
+		rpcErr := &RPCError{
+			Method: method,
+			Result: answer.Error.Data.ErrorString,
+			Tag:    tag,
+			Code:   answer.Error.Code,
+		}
+		if rpcErr.Result == "" {
+			rpcErr.Result = answer.Error.Message
+		}
+		return rpcErr
+	}
+	if result == nil || len(answer.Result) == 0 || bytes.Equal(answer.Result, []byte("null")) {
+		return
+	}
+	// Translate the snake case names back to their legacy form before decoding into the library types
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(answer.Result))
+	decoder.UseNumber()
+	if err = decoder.Decode(&generic); err != nil {
+		return fmt.Errorf("can't unmarshal answer result: %w", err)
+	}
+	legacy, err := json.Marshal(translateAnswer(generic, reflect.TypeOf(result)))
+	if err != nil {
+		return fmt.Errorf("can't translate answer result: %w", err)
+	}
+	if err = json.Unmarshal(legacy, result); err != nil {
+		return fmt.Errorf("can't unmarshal answer result: %w", err)
+	}
+	return
+}
+
+/*
+	Names translation
+*/
+
+// toSnakeCase converts a legacy name ("download-dir", "hashString", "isUTP") to its snake case form.
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	builder.Grow(len(name) + 4)
+	for index, r := range runes {
+		switch {
+		case r == '-':
+			builder.WriteByte('_')
+		case unicode.IsUpper(r):
+			if index > 0 && runes[index-1] != '-' && runes[index-1] != '_' &&
+				(!unicode.IsUpper(runes[index-1]) || (index+1 < len(runes) && unicode.IsLower(runes[index+1]))) {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// legacyNames maps the snake case names to the legacy ones, built from the JSON tags of the types
+// that can be found within the answers. Used when the answer type does not tell which name to use.
+var legacyNames = make(map[string]string)
+
+func init() {
+	visited := make(map[reflect.Type]bool)
+	for _, answerType := range []interface{}{
+		Torrent{},
+		torrentGetResults{},
+		torrentAddAnswer{},
+		SessionArguments{},
+		SessionStats{},
+		transmissionFreeSpacePayload{},
+		portTestAnswer{},
+		blocklistUpdateAnswer{},
+		bandwidthGroupGetAnswer{},
+	} {
+		registerLegacyNames(reflect.TypeOf(answerType), visited)
+	}
+}
+
+func registerLegacyNames(t reflect.Type, visited map[reflect.Type]bool) {
+	t = containedType(t)
+	if t.Kind() != reflect.Struct || visited[t] {
+		return
+	}
+	visited[t] = true
+	for snake, field := range structLegacyNames(t) {
+		if _, found := legacyNames[snake]; !found {
+			legacyNames[snake] = field.name
+		}
+		registerLegacyNames(field.fieldType, visited)
+	}
+}
+
+// containedType returns the type of the values contained by pointers, slices, arrays and maps.
+func containedType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	return t
+}
+
+type legacyField struct {
+	name      string
+	fieldType reflect.Type
+}
+
+var structsLegacyNames sync.Map // reflect.Type -> map[string]legacyField
+
+// structLegacyNames returns the legacy name and type of the fields of a struct, by snake case name.
+func structLegacyNames(t reflect.Type) (fields map[string]legacyField) {
+	if cached, found := structsLegacyNames.Load(t); found {
+		return cached.(map[string]legacyField)
+	}
+	fields = make(map[string]legacyField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			fields[toSnakeCase(name)] = legacyField{
+				name:      name,
+				fieldType: field.Type,
+			}
+		}
+	}
+	structsLegacyNames.Store(t, fields)
+	return
+}
+
+/*
+	Requests translation
+*/
+
+// translateRequest marshals the arguments and rewrites their names in snake case.
+func translateRequest(arguments interface{}) (translated interface{}, err error) {
+	raw, err := json.Marshal(arguments)
+	if err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(&translated); err != nil {
+		return
+	}
+	return translateRequestValue("", translated), nil
+}
+
+func translateRequestValue(key string, value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		translated := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			translated[toSnakeCase(k)] = translateRequestValue(k, v)
+		}
+		return translated
+	case []interface{}:
+		for index, v := range typed {
+			typed[index] = translateRequestValue(key, v)
+		}
+		return typed
+	case string:
+		// values which are names themselves: requested fields and the recently active ids selector
+		if key == "fields" || (key == "ids" && typed == "recently-active") {
+			return toSnakeCase(typed)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+/*
+	Answers translation
+*/
+
+var (
+	torrentType      = reflect.TypeOf(Torrent{})
+	rawMessageType   = reflect.TypeOf(json.RawMessage{})
+	torrentTableType = reflect.TypeOf([][]json.RawMessage{})
+)
+
+// translateAnswer translates the snake case names of an answer back to their legacy names. The
+// type the answer will be decoded into (nil if unknown) tells which legacy name to use for each
+// object: some snake case names have several legacy forms ("download_dir" is "downloadDir" for
+// a torrent but "download-dir" for the session).
+func translateAnswer(value interface{}, t reflect.Type) interface{} {
+	if t != nil {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == rawMessageType || t.Kind() == reflect.Interface {
+			t = nil
+		}
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		translated := make(map[string]interface{}, len(typed))
+		var fields map[string]legacyField
+		var elemType reflect.Type
+		if t != nil {
+			switch t.Kind() {
+			case reflect.Struct:
+				fields = structLegacyNames(t)
+			case reflect.Map:
+				elemType = t.Elem()
+			}
+		}
+		for key, v := range typed {
+			if field, found := fields[key]; found {
+				if field.fieldType == torrentTableType {
+					translated[field.name] = translateTorrentTable(v)
+				} else {
+					translated[field.name] = translateAnswer(v, field.fieldType)
+				}
+			} else if elemType != nil {
+				translated[key] = translateAnswer(v, elemType)
+			} else if fields != nil {
+				// field without JSON tag (decoded by a custom unmarshaller): legacy names are camel case
+				translated[legacyFieldName(key)] = translateAnswer(v, nil)
+			} else {
+				translated[legacyName(key)] = translateAnswer(v, nil)
+			}
+		}
+		return translated
+	case []interface{}:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		for index, v := range typed {
+			typed[index] = translateAnswer(v, elemType)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// translateTorrentTable translates a torrent-get table format answer: the first row contains
+// the fields names, the other ones their values for each torrent.
+func translateTorrentTable(value interface{}) interface{} {
+	rows, ok := value.([]interface{})
+	if !ok || len(rows) == 0 {
+		return translateAnswer(value, nil)
+	}
+	header, ok := rows[0].([]interface{})
+	if !ok {
+		return translateAnswer(value, nil)
+	}
+	fields := structLegacyNames(torrentType)
+	types := make([]reflect.Type, len(header))
+	for index, name := range header {
+		if str, ok := name.(string); ok {
+			if field, found := fields[str]; found {
+				header[index] = field.name
+				types[index] = field.fieldType
+			} else {
+				header[index] = legacyName(str)
+			}
+		}
+	}
+	for _, row := range rows[1:] {
+		if cells, ok := row.([]interface{}); ok {
+			for index, cell := range cells {
+				if index < len(types) {
+					cells[index] = translateAnswer(cell, types[index])
+				}
+			}
+		}
+	}
+	return rows
+}
+
+func legacyName(name string) string {
+	if legacy, found := legacyNames[name]; found {
+		return legacy
+	}
+	return name
+}
+
+func legacyFieldName(name string) string {
+	if legacy, found := legacyNames[name]; found {
+		return legacy
+	}
+	parts := strings.Split(name, "_")
+	for index := 1; index < len(parts); index++ {
+		if parts[index] != "" {
+			parts[index] = strings.ToUpper(parts[index][:1]) + parts[index][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
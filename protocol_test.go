@@ -0,0 +1,190 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+// jsonRPCDaemon is a minimal JSON-RPC 2.0 daemon answering with canned snake case results.
+type jsonRPCDaemon struct {
+	server  *httptest.Server
+	results map[string]string // method -> raw result
+	mutex   sync.Mutex
+	params  map[string]json.RawMessage // method -> last params received
+}
+
+func newJSONRPCDaemon(t *testing.T, results map[string]string) *jsonRPCDaemon {
+	t.Helper()
+	daemon := &jsonRPCDaemon{
+		results: results,
+		params:  make(map[string]json.RawMessage),
+	}
+	daemon.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params"`
+			ID      int             `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.JSONRPC != "2.0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		daemon.mutex.Lock()
+		daemon.params[request.Method] = request.Params
+		daemon.mutex.Unlock()
+		result, found := daemon.results[request.Method]
+		if !found {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"error":   map[string]interface{}{"code": -32601, "message": "Method not found"},
+				"id":      request.ID,
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"result":  json.RawMessage(result),
+			"id":      request.ID,
+		})
+	}))
+	t.Cleanup(daemon.server.Close)
+	return daemon
+}
+
+func (d *jsonRPCDaemon) client(t *testing.T, config transmissionrpc.Config) *transmissionrpc.Client {
+	t.Helper()
+	endpoint, _ := url.Parse(d.server.URL + "/transmission/rpc")
+	config.Protocol = transmissionrpc.ProtocolJSONRPC
+	client, err := transmissionrpc.New(endpoint, &config)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	return client
+}
+
+func (d *jsonRPCDaemon) lastParams(t *testing.T, method string) (params map[string]interface{}) {
+	t.Helper()
+	d.mutex.Lock()
+	raw, found := d.params[method]
+	d.mutex.Unlock()
+	if !found {
+		t.Fatalf("no '%s' request received", method)
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		t.Fatalf("can't decode '%s' params: %v", method, err)
+	}
+	return
+}
+
+const jsonRPCSessionGetResult = `{"rpc_version":18,"rpc_version_minimum":14,"version":"4.1.0","download_dir":"/session/dir","peer_limit_global":200}`
+
+func TestJSONRPCTorrentNames(t *testing.T) {
+	daemon := newJSONRPCDaemon(t, map[string]string{
+		"session_get": jsonRPCSessionGetResult,
+		"torrent_get": `{"torrents":[{"id":1,"hash_string":"abc","download_dir":"/torrent/dir","percent_done":0.5}],"removed":[2]}`,
+		"torrent_set": `{}`,
+	})
+	client := daemon.client(t, transmissionrpc.Config{})
+	ctx := context.Background()
+	// requests
+	torrents, removed, err := client.TorrentGetRecentlyActive(ctx, []string{"id", "hashString", "downloadDir", "percentDone"})
+	if err != nil {
+		t.Fatalf("torrent-get failed: %v", err)
+	}
+	params := daemon.lastParams(t, "torrent_get")
+	if fields := params["fields"]; !reflect.DeepEqual(fields, []interface{}{"id", "hash_string", "download_dir", "percent_done"}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	if ids := params["ids"]; ids != "recently_active" {
+		t.Fatalf("unexpected ids: %v", ids)
+	}
+	// answers
+	if len(torrents) != 1 || *torrents[0].HashString != "abc" || *torrents[0].DownloadDir != "/torrent/dir" || *torrents[0].PercentDone != 0.5 {
+		t.Fatalf("unexpected torrents: %+v", torrents)
+	}
+	if len(removed) != 1 || removed[0] != 2 {
+		t.Fatalf("unexpected removed ids: %v", removed)
+	}
+	// mutators
+	downloadLimit := int64(100)
+	err = client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+		IDs:           []int64{1},
+		DownloadLimit: &downloadLimit,
+		Labels:        []string{"linux"},
+	})
+	if err != nil {
+		t.Fatalf("torrent-set failed: %v", err)
+	}
+	params = daemon.lastParams(t, "torrent_set")
+	if params["download_limit"] != float64(100) || !reflect.DeepEqual(params["ids"], []interface{}{float64(1)}) ||
+		!reflect.DeepEqual(params["labels"], []interface{}{"linux"}) {
+		t.Fatalf("unexpected torrent_set params: %v", params)
+	}
+	if _, found := params["downloadLimit"]; found {
+		t.Fatalf("legacy name sent: %v", params)
+	}
+}
+
+func TestJSONRPCSessionNames(t *testing.T) {
+	daemon := newJSONRPCDaemon(t, map[string]string{
+		"session_get": jsonRPCSessionGetResult,
+		"session_set": `{}`,
+	})
+	client := daemon.client(t, transmissionrpc.Config{})
+	ctx := context.Background()
+	// "download_dir" is "download-dir" for the session but "downloadDir" for the torrents
+	args, err := client.SessionArgumentsGet(ctx, []string{"download-dir", "peer-limit-global"})
+	if err != nil {
+		t.Fatalf("session-get failed: %v", err)
+	}
+	if args.DownloadDir == nil || *args.DownloadDir != "/session/dir" || args.PeerLimitGlobal == nil || *args.PeerLimitGlobal != 200 {
+		t.Fatalf("unexpected session arguments: %+v", args)
+	}
+	if fields := daemon.lastParams(t, "session_get")["fields"]; !reflect.DeepEqual(fields, []interface{}{"download_dir", "peer_limit_global"}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	downloadDir := "/data"
+	if err = client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{DownloadDir: &downloadDir}); err != nil {
+		t.Fatalf("session-set failed: %v", err)
+	}
+	if params := daemon.lastParams(t, "session_set"); len(params) != 1 || params["download_dir"] != downloadDir {
+		t.Fatalf("unexpected session_set params: %v", params)
+	}
+}
+
+func TestJSONRPCTableFormat(t *testing.T) {
+	daemon := newJSONRPCDaemon(t, map[string]string{
+		"session_get": jsonRPCSessionGetResult,
+		"torrent_get": `{"torrents":[["id","download_dir","hash_string"],[1,"/torrent/dir","abc"],[2,"/other/dir","def"]]}`,
+	})
+	client := daemon.client(t, transmissionrpc.Config{TableFormat: true})
+	torrents, err := client.TorrentGet(context.Background(), []string{"id", "downloadDir", "hashString"}, nil)
+	if err != nil {
+		t.Fatalf("torrent-get failed: %v", err)
+	}
+	if daemon.lastParams(t, "torrent_get")["format"] != "table" {
+		t.Fatal("table format not requested")
+	}
+	if len(torrents) != 2 || *torrents[1].ID != 2 || *torrents[1].DownloadDir != "/other/dir" || *torrents[1].HashString != "def" {
+		t.Fatalf("unexpected torrents: %+v", torrents)
+	}
+}
+
+func TestJSONRPCErrors(t *testing.T) {
+	daemon := newJSONRPCDaemon(t, nil)
+	client := daemon.client(t, transmissionrpc.Config{})
+	_, err := client.SessionStats(context.Background())
+	if !errors.Is(err, transmissionrpc.ErrMethodNotRecognized) {
+		t.Fatalf("expected ErrMethodNotRecognized, got %v", err)
+	}
+}
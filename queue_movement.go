@@ -46,6 +46,37 @@ func (c *Client) QueueMoveBottom(ctx context.Context, IDs []int64) (err error) {
 	return
 }
 
+// QueueMoveTopFor moves the torrent(s) targeted by the selector to the top of the queue list.
+func (c *Client) QueueMoveTopFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.queueMove(ctx, "queue-move-top", selector)
+}
+
+// QueueMoveUpFor moves the torrent(s) targeted by the selector of one position up on the queue list.
+func (c *Client) QueueMoveUpFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.queueMove(ctx, "queue-move-up", selector)
+}
+
+// QueueMoveDownFor moves the torrent(s) targeted by the selector of one position down on the queue list.
+func (c *Client) QueueMoveDownFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.queueMove(ctx, "queue-move-down", selector)
+}
+
+// QueueMoveBottomFor moves the torrent(s) targeted by the selector to the bottom of the queue list.
+func (c *Client) QueueMoveBottomFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.queueMove(ctx, "queue-move-bottom", selector)
+}
+
+func (c *Client) queueMove(ctx context.Context, method string, selector IDSelector) (err error) {
+	ids, err := selector.explicitPayload()
+	if err != nil {
+		return
+	}
+	if err = c.rpcCall(ctx, method, &torrentSelectorParam{IDs: ids}, nil); err != nil {
+		err = fmt.Errorf("'%s' rpc method failed: %w", method, err)
+	}
+	return
+}
+
 type queueMovePayload struct {
 	IDs []int64 `json:"ids"`
 }
@@ -0,0 +1,206 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+	Rate limiting
+	Protects low-powered daemons from being hammered by too many (concurrent) calls.
+*/
+
+// readMethods are the RPC methods not modifying the daemon state. All the others are writes.
+var readMethods = map[string]bool{
+	"torrent-get":   true,
+	"session-get":   true,
+	"session-stats": true,
+	"free-space":    true,
+	"group-get":     true,
+	"port-test":     true,
+}
+
+// Limits defines how many calls can be sent to the daemon. Zero values mean unlimited.
+type Limits struct {
+	// RequestsPerSecond is the rate at which calls can be sent (token bucket).
+	RequestsPerSecond float64
+	// Burst is the number of calls which can be sent at once before being limited by
+	// RequestsPerSecond. Defaults to 1.
+	Burst int
+	// MaxInFlight is the maximum number of calls waiting for their answer at the same time.
+	// A TorrentGetIterator() call leaves them once the answer headers are received.
+	MaxInFlight int
+}
+
+// RateLimit limits the calls sent to the daemon. Waiting for a call to be allowed honors the
+// call context. Retries (see RetryPolicy) happen within the allowed call.
+type RateLimit struct {
+	// Limits applies to every call.
+	Limits
+	// Reads, if set, additionally applies to the calls not modifying the daemon state
+	// (torrent-get, session-get, session-stats, free-space, group-get and port-test).
+	Reads *Limits
+	// Writes, if set, additionally applies to all the other calls (torrent-set, torrent-add, etc...).
+	Writes *Limits
+}
+
+// rateLimiter enforces a RateLimit.
+type rateLimiter struct {
+	all    *callLimiter
+	reads  *callLimiter
+	writes *callLimiter
+}
+
+func newRateLimiter(rl RateLimit) (limiter *rateLimiter, err error) {
+	limiter = new(rateLimiter)
+	if limiter.all, err = newCallLimiter(rl.Limits); err != nil {
+		return nil, err
+	}
+	if rl.Reads != nil {
+		if limiter.reads, err = newCallLimiter(*rl.Reads); err != nil {
+			return nil, fmt.Errorf("invalid reads limits: %w", err)
+		}
+	}
+	if rl.Writes != nil {
+		if limiter.writes, err = newCallLimiter(*rl.Writes); err != nil {
+			return nil, fmt.Errorf("invalid writes limits: %w", err)
+		}
+	}
+	return
+}
+
+// acquire waits for method to be allowed. On success, release must be called once the call is done.
+// Limiters are always acquired in the same order (method class first) to avoid deadlocks.
+func (rl *rateLimiter) acquire(ctx context.Context, method string) (release func(), err error) {
+	class := rl.writes
+	if readMethods[method] {
+		class = rl.reads
+	}
+	if err = class.acquire(ctx); err != nil {
+		return
+	}
+	if err = rl.all.acquire(ctx); err != nil {
+		class.release()
+		return
+	}
+	release = func() {
+		rl.all.release()
+		class.release()
+	}
+	return
+}
+
+// acquireCall waits for the client rate limit (if any) to allow method.
+func (c *Client) acquireCall(ctx context.Context, method string) (release func(), err error) {
+	if c.rateLimiter == nil {
+		return func() {}, nil
+	}
+	if release, err = c.rateLimiter.acquire(ctx, method); err != nil {
+		err = fmt.Errorf("rate limit wait interrupted: %w", err)
+	}
+	return
+}
+
+/*
+	Limiters
+*/
+
+// callLimiter combines a token bucket and an in flight semaphore. A nil limiter is unlimited.
+type callLimiter struct {
+	bucket   *tokenBucket
+	inFlight chan struct{}
+}
+
+func newCallLimiter(limits Limits) (limiter *callLimiter, err error) {
+	if limits.RequestsPerSecond < 0 || limits.Burst < 0 || limits.MaxInFlight < 0 {
+		return nil, errors.New("limits can not be negative")
+	}
+	if limits.RequestsPerSecond == 0 && limits.MaxInFlight == 0 {
+		return
+	}
+	limiter = new(callLimiter)
+	if limits.RequestsPerSecond > 0 {
+		limiter.bucket = newTokenBucket(limits.RequestsPerSecond, limits.Burst)
+	}
+	if limits.MaxInFlight > 0 {
+		limiter.inFlight = make(chan struct{}, limits.MaxInFlight)
+	}
+	return
+}
+
+func (cl *callLimiter) acquire(ctx context.Context) (err error) {
+	if cl == nil {
+		return
+	}
+	if cl.inFlight != nil {
+		select {
+		case cl.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if cl.bucket != nil {
+		if err = cl.bucket.wait(ctx); err != nil && cl.inFlight != nil {
+			<-cl.inFlight
+		}
+	}
+	return
+}
+
+func (cl *callLimiter) release() {
+	if cl != nil && cl.inFlight != nil {
+		<-cl.inFlight
+	}
+}
+
+// tokenBucket allows rate events per second with bursts of burst events. Waiting callers reserve
+// their token: they are served in order.
+type tokenBucket struct {
+	access sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (tb *tokenBucket) wait(ctx context.Context) (err error) {
+	tb.access.Lock()
+	now := time.Now()
+	tb.tokens = math.Min(tb.burst, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	tb.last = now
+	tb.tokens--
+	if tb.tokens >= 0 {
+		tb.access.Unlock()
+		return
+	}
+	delay := time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+	tb.access.Unlock()
+	timer := time.NewTimer(delay)
+	select {
+	case <-timer.C:
+		return
+	case <-ctx.Done():
+		timer.Stop()
+		// give back the reserved token
+		tb.access.Lock()
+		tb.tokens = math.Min(tb.burst, tb.tokens+1)
+		tb.access.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,56 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurstAndRate(t *testing.T) {
+	bucket := newTokenBucket(20, 2)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := bucket.wait(context.Background()); err != nil {
+			t.Fatalf("burst wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("burst took %v, expected no wait", elapsed)
+	}
+	if err := bucket.wait(context.Background()); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("third token obtained after %v, expected about 50ms", elapsed)
+	}
+}
+
+func TestTokenBucketCanceledWaitGivesBackItsToken(t *testing.T) {
+	bucket := newTokenBucket(1, 1)
+	if err := bucket.wait(context.Background()); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	// the reservation of the next token (1s away) is given back on cancellation
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	bucket.access.Lock()
+	tokens := bucket.tokens
+	bucket.access.Unlock()
+	if tokens < -0.1 {
+		t.Fatalf("expected the reserved token to be given back, %f tokens left", tokens)
+	}
+}
+
+func TestCallLimiterValidation(t *testing.T) {
+	if _, err := newCallLimiter(Limits{MaxInFlight: -1}); err == nil {
+		t.Fatal("expected negative limits to be rejected")
+	}
+	limiter, err := newCallLimiter(Limits{})
+	if err != nil || limiter != nil {
+		t.Fatalf("expected zero limits to be unlimited (nil), got %v and %v", limiter, err)
+	}
+}
@@ -0,0 +1,156 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+// concurrencyRecorder records the maximum number of concurrent requests going through it.
+type concurrencyRecorder struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+func (cr *concurrencyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	cr.mutex.Lock()
+	cr.current++
+	if cr.current > cr.max {
+		cr.max = cr.current
+	}
+	cr.mutex.Unlock()
+	defer func() {
+		cr.mutex.Lock()
+		cr.current--
+		cr.mutex.Unlock()
+	}()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRateLimitMaxInFlight(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.SetLatency(20 * time.Millisecond)
+	recorder := new(concurrencyRecorder)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		CustomClient: &http.Client{Transport: recorder},
+		RateLimit:    &transmissionrpc.RateLimit{Limits: transmissionrpc.Limits{MaxInFlight: 2}},
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.SessionStats(context.Background())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	if recorder.max > 2 {
+		t.Fatalf("expected at most 2 requests in flight, got %d", recorder.max)
+	}
+}
+
+func TestRateLimitMethodClasses(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		RateLimit: &transmissionrpc.RateLimit{Writes: &transmissionrpc.Limits{MaxInFlight: 1}},
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("can't connect: %v", err)
+	}
+	// a write in flight: held by the daemon until released
+	received := make(chan struct{})
+	release := make(chan struct{})
+	daemon.OnRequest(func(method string, arguments json.RawMessage) {
+		if method == "torrent-stop" {
+			close(received)
+			<-release
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- client.TorrentStopIDs(context.Background(), nil)
+	}()
+	<-received
+	// another write waits for it
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.TorrentStartIDs(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second write to wait until its deadline, got %v", err)
+	}
+	if count := countRequests(daemon, "torrent-start"); count != 0 {
+		t.Fatalf("expected the second write not to be sent, got %d torrent-start", count)
+	}
+	// reads are not limited
+	if _, err := client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first write failed: %v", err)
+	}
+}
+
+func TestRateLimitRequestsPerSecond(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		RateLimit: &transmissionrpc.RateLimit{Limits: transmissionrpc.Limits{RequestsPerSecond: 20, Burst: 2}},
+	})
+	// the burst allows the first two calls, the next ones are spaced by 50ms
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.SessionStats(context.Background()); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("4 calls took %v, expected at least 100ms", elapsed)
+	}
+}
+
+func TestRateLimitIteratorReleasesItsSlot(t *testing.T) {
+	daemon := newTestDaemon(t)
+	for _, name := range []string{"first", "second"} {
+		daemon.AddTorrent(map[string]interface{}{"name": name})
+	}
+	client := newTestClient(t, daemon, &transmissionrpc.Config{
+		RateLimit: &transmissionrpc.RateLimit{Limits: transmissionrpc.Limits{MaxInFlight: 1}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	it, err := client.TorrentGetIterator(ctx, []string{"id"}, nil)
+	if err != nil {
+		t.Fatalf("can't get iterator: %v", err)
+	}
+	defer it.Close()
+	var count int
+	for it.Next() {
+		if err = client.TorrentStopIDs(ctx, []int64{*it.Torrent().ID}); err != nil {
+			t.Fatalf("can't stop torrent while iterating: %v", err)
+		}
+		count++
+	}
+	if err = it.Err(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 torrents, got %d", count)
+	}
+}
@@ -3,7 +3,6 @@ package transmissionrpc
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -24,77 +23,161 @@ type answerPayload struct {
 }
 
 func (c *Client) rpcCall(ctx context.Context, method string, arguments interface{}, result interface{}) (err error) {
-	return c.request(ctx, method, arguments, result, true)
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	c.discoverProtocol(ctx, method)
+	release, err := c.acquireCall(ctx, method)
+	if err != nil {
+		return
+	}
+	defer release()
+	return c.invoke(ctx, method, arguments, result, func(ctx context.Context, method string, arguments, result interface{}) error {
+		return c.withRetry(ctx, method, func() error {
+			return c.request(ctx, method, arguments, result, true)
+		})
+	})
 }
 
 func (c *Client) request(ctx context.Context, method string, arguments interface{}, result interface{}, retry bool) (err error) {
+	protocol := c.wireProtocol()
+	if c.reads != nil {
+		if coalescableMethods[method] && (c.reads.coalesce || c.reads.sessionTTL > 0) {
+			return c.sharedRequest(ctx, protocol, method, arguments, result, retry)
+		}
+		if !readMethods[method] {
+			// before and after: a session-get may be fetched while the daemon applies the call
+			c.reads.invalidate()
+			defer c.reads.invalidate()
+		}
+	}
+	// Send the request
+	resp, tag, _, err := c.send(ctx, protocol, method, arguments, retry)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	// Decode body & final checks
+	return protocol.decodeAnswer(method, resp.Body, result, tag)
+}
+
+// send executes the HTTP request and returns the (successful) HTTP response with the tag used within
+// the request payload and the endpoint which answered. Caller is responsible for closing the response body.
+func (c *Client) send(ctx context.Context, protocol wireProtocol, method string, arguments interface{}, retry bool) (resp *http.Response, tag int, served *rpcEndpoint, err error) {
 	// Let's avoid crashing if not instanciated properly
-	if c.http == nil {
+	if len(c.endpoints) == 0 {
 		err = errors.New("this controller is not initialized, please use the New() function")
 		return
 	}
 	// Prepare request payload
-	rq := requestPayload{
-		Method:    method,
-		Arguments: arguments,
-		Tag:       c.getRandomTag(),
-	}
-	rqJSON, err := json.Marshal(rq)
+	requestTag := c.getRandomTag()
+	rqJSON, err := protocol.encodeRequest(method, arguments, requestTag)
 	if err != nil {
 		err = fmt.Errorf("failed to marshal request payload: %w", err)
 		return
 	}
+	// Send it to the first endpoint able to answer
+	for _, endpoint := range c.candidateEndpoints() {
+		if resp, err = c.sendTo(ctx, endpoint, method, rqJSON, retry); err == nil {
+			if len(c.endpoints) > 1 {
+				endpoint.markUp()
+			}
+			recordServedEndpoint(ctx, endpoint)
+			tag = requestTag
+			served = endpoint
+			return
+		}
+		if len(c.endpoints) == 1 || ctx.Err() != nil || !shouldFailover(method, err) {
+			return
+		}
+		endpoint.markDown(err, c.failoverCooldown)
+	}
+	return
+}
+
+// sendTo executes the HTTP request against one endpoint, handling the CSRF handshake.
+func (c *Client) sendTo(ctx context.Context, endpoint *rpcEndpoint, method string, rqJSON []byte, retry bool) (resp *http.Response, err error) {
+	sessionID, leader, err := endpoint.sessionIDFor(ctx)
+	if err != nil {
+		err = fmt.Errorf("can't get a session id for '%s' method: %w", method, err)
+		return
+	}
+	if leader {
+		defer endpoint.endHandshake()
+	}
+	return c.post(ctx, endpoint, method, rqJSON, sessionID, retry)
+}
+
+func (c *Client) post(ctx context.Context, endpoint *rpcEndpoint, method string, rqJSON []byte, sessionID string, retry bool) (resp *http.Response, err error) {
 	// Build the request
 	var req *http.Request
-	if req, err = http.NewRequestWithContext(ctx, "POST", c.endpoint.String(), bytes.NewBuffer(rqJSON)); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, "POST", endpoint.url.String(), bytes.NewBuffer(rqJSON)); err != nil {
 		err = fmt.Errorf("can't prepare request for '%s' method: %w", method, err)
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set(csrfHeader, c.getSessionID())
+	req.Header.Set(csrfHeader, sessionID)
+	applyHeaders(ctx, req.Header)
+	if c.auth != nil {
+		if err = c.auth.Authenticate(req); err != nil {
+			err = fmt.Errorf("can't authenticate request for '%s' method: %w", method, err)
+			return
+		}
+	}
 	// Execute request
-	var resp *http.Response
-	if resp, err = c.http.Do(req); err != nil {
+	if resp, err = endpoint.http.Do(req); err != nil {
 		err = fmt.Errorf("failed to execute HTTP request: %w", err)
 		return
 	}
-	defer resp.Body.Close()
 	// Is the CRSF token invalid ?
 	if resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
 		// Recover new token and save it
-		c.updateSessionID(resp.Header.Get(csrfHeader))
+		current, stale := c.handleConflict(endpoint, resp, sessionID)
+		// The handshake of a client without session id is not a rejection
+		if sessionID != "" && callOptionsFromContext(ctx).noCSRFRetry {
+			resp = nil
+			err = HTTPStatusCode(http.StatusConflict)
+			return
+		}
+		// Another call already refreshed it: not our failure
+		if stale {
+			return c.post(ctx, endpoint, method, rqJSON, current, retry)
+		}
 		// Retry request if first try
 		if retry {
-			return c.request(ctx, method, arguments, result, false)
+			return c.post(ctx, endpoint, method, rqJSON, current, false)
 		}
-		err = errors.New("CSRF token invalid 2 times in a row: stopping to avoid infinite loop")
+		resp = nil
+		err = ErrCSRFLoop
 		return
 	}
 	// Is request successful ?
 	if resp.StatusCode != 200 {
+		resp.Body.Close()
 		err = HTTPStatusCode(resp.StatusCode)
+		resp = nil
 		return
 	}
-	// Decode body
-	answer := answerPayload{
-		Arguments: result,
-	}
-	if err = json.NewDecoder(resp.Body).Decode(&answer); err != nil {
-		err = fmt.Errorf("can't unmarshal request answer body: %w", err)
-		return
-	}
-	// Final checks
-	if answer.Tag == nil {
-		err = errors.New("http answer does not have a tag within it's payload")
+	return
+}
+
+// checkAnswer validates the decoded answer payload tag and result against the request tag.
+func checkAnswer(method, result string, answerTag *int, requestTag int) (err error) {
+	if answerTag == nil {
+		err = ErrMissingTag
 		return
 	}
-	if *answer.Tag != rq.Tag {
-		err = errors.New("http request tag and answer payload tag do not match")
+	if *answerTag != requestTag {
+		err = ErrTagMismatch
 		return
 	}
-	if answer.Result != "success" {
-		err = fmt.Errorf("http request ok but payload does not indicate success: %s", answer.Result)
+	if result != "success" {
+		err = &RPCError{
+			Method: method,
+			Result: result,
+			Tag:    requestTag,
+		}
 		return
 	}
 	// All good
@@ -111,3 +194,15 @@ func (hsc HTTPStatusCode) Error() string {
 	}
 	return fmt.Sprintf("HTTP error %d%s", hsc, text)
 }
+
+// Is allows to match 401 and 421 status codes with ErrUnauthorized and ErrMisdirected.
+func (hsc HTTPStatusCode) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return hsc == http.StatusUnauthorized
+	case ErrMisdirected:
+		return hsc == http.StatusMisdirectedRequest
+	default:
+		return false
+	}
+}
@@ -0,0 +1,193 @@
+package transmissionrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+/*
+	Retry policy
+	Allows to retry transient failures (daemon restarting, reverse proxy errors, etc...).
+*/
+
+const (
+	defaultRetryInitialBackoff = 250 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+	defaultRetryMultiplier     = 2
+)
+
+// nonIdempotentMethods are the RPC methods that can not be blindly replayed: replaying them
+// after the daemon has (maybe) processed the first attempt would change the outcome.
+var nonIdempotentMethods = map[string]bool{
+	"torrent-add":         true,
+	"torrent-remove":      true,
+	"torrent-rename-path": true,
+	"queue-move-up":       true,
+	"queue-move-down":     true,
+	"session-close":       true,
+}
+
+// RetryPolicy defines how failed RPC calls are retried. The 409 CSRF handshake is handled
+// separately and does not count as an attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts for a call, including the first one.
+	// A value of 0 or 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Defaults to 250ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts. Defaults to 10s.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after each retry. Defaults to 2.
+	Multiplier float64
+	// Jitter randomizes each delay by +/- this fraction (between 0 and 1) of its value.
+	Jitter float64
+	// RetryOnStatus decides if an HTTP status code answer should be retried.
+	// Defaults to 502, 503 and 504 (reverse proxy in front of a restarting daemon).
+	RetryOnStatus func(statusCode int) bool
+	// RetryOnError decides if an error which is not an HTTP status code should be retried.
+	// Defaults to network errors (connection refused, reset, timeouts, etc...) as long as the
+	// call context is not done. TLS and certificate failures are not retried.
+	RetryOnError func(err error) bool
+	// RetryNonIdempotent allows to retry methods that can not be safely replayed (torrent-add,
+	// torrent-remove, torrent-rename-path, queue-move-up, queue-move-down and session-close)
+	// for any retryable failure. Otherwise they are only retried when the daemon could not be
+	// reached at all (connection failures), meaning the request was never received.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a policy of 4 attempts with an exponential backoff and 20% jitter.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+		Multiplier:     defaultRetryMultiplier,
+		Jitter:         0.2,
+	}
+}
+
+// shouldRetry returns true if the failed attempt should be retried.
+func (rp *RetryPolicy) shouldRetry(method string, err error) bool {
+	var retryable bool
+	var statusCode HTTPStatusCode
+	if errors.As(err, &statusCode) {
+		if rp.RetryOnStatus != nil {
+			retryable = rp.RetryOnStatus(int(statusCode))
+		} else {
+			retryable = defaultRetryOnStatus(int(statusCode))
+		}
+	} else {
+		if rp.RetryOnError != nil {
+			retryable = rp.RetryOnError(err)
+		} else {
+			retryable = defaultRetryOnError(err)
+		}
+	}
+	if !retryable {
+		return false
+	}
+	if nonIdempotentMethods[method] && !rp.RetryNonIdempotent {
+		return requestNotSent(err)
+	}
+	return true
+}
+
+// backoff returns the delay to wait before the given retry (starting at 1).
+func (rp *RetryPolicy) backoff(retry int, random func() float64) (delay time.Duration) {
+	initial := rp.InitialBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	maximum := rp.MaxBackoff
+	if maximum <= 0 {
+		maximum = defaultRetryMaxBackoff
+	}
+	multiplier := rp.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+	computed := float64(initial)
+	for i := 1; i < retry && computed < float64(maximum); i++ {
+		computed *= multiplier
+	}
+	if computed > float64(maximum) {
+		computed = float64(maximum)
+	}
+	if rp.Jitter > 0 {
+		computed += (random()*2 - 1) * rp.Jitter * computed
+	}
+	return time.Duration(computed)
+}
+
+func defaultRetryOnStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func defaultRetryOnError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// TLS and certificate failures won't go away by themselves
+	var (
+		recordHeaderErr tls.RecordHeaderError
+		unknownAuthErr  x509.UnknownAuthorityError
+		hostnameErr     x509.HostnameError
+		certInvalidErr  x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordHeaderErr) || errors.As(err, &unknownAuthErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &certInvalidErr) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// the HTTP client errors are net.Error too: only their timeouts are transient
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// requestNotSent returns true if the error indicates the daemon could not be reached at all.
+func requestNotSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// withRetry executes call according to the client retry policy (if any).
+func (c *Client) withRetry(ctx context.Context, method string, call func() error) (err error) {
+	if c.retryPolicy == nil || c.retryPolicy.MaxAttempts <= 1 {
+		return call()
+	}
+	var timer *time.Timer
+	for attempt := 1; ; attempt++ {
+		if err = call(); err == nil {
+			return
+		}
+		if attempt >= c.retryPolicy.MaxAttempts || ctx.Err() != nil || !c.retryPolicy.shouldRetry(method, err) {
+			break
+		}
+		timer = time.NewTimer(c.retryPolicy.backoff(attempt, c.tagGenerator.Float64))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry interrupted after %d attempt(s): %w", attempt, err)
+		case <-timer.C:
+		}
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package transmissionrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// timeoutError is a net.Error timing out, like the ones of the HTTP client timeouts.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestDefaultRetryOnError(t *testing.T) {
+	httpErr := func(err error) error {
+		return &url.Error{Op: "Post", URL: "http://localhost:9091/transmission/rpc", Err: err}
+	}
+	for _, tc := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "connection refused", err: httpErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), retryable: true},
+		{name: "connection reset", err: httpErr(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), retryable: true},
+		{name: "network error", err: httpErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "seedbox"}}), retryable: true},
+		{name: "timeout", err: httpErr(timeoutError{}), retryable: true},
+		{name: "canceled", err: httpErr(context.Canceled), retryable: false},
+		{name: "deadline exceeded", err: httpErr(context.DeadlineExceeded), retryable: false},
+		{name: "TLS to a plain HTTP server", err: httpErr(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), retryable: false},
+		{name: "unknown certificate authority", err: httpErr(x509.UnknownAuthorityError{}), retryable: false},
+		{name: "certificate hostname mismatch", err: httpErr(x509.HostnameError{Host: "seedbox"}), retryable: false},
+		{name: "invalid certificate", err: httpErr(x509.CertificateInvalidError{Reason: x509.Expired}), retryable: false},
+		{name: "other HTTP client error", err: httpErr(io.ErrUnexpectedEOF), retryable: false},
+		{name: "not a network error", err: errors.New("can't decode answer"), retryable: false},
+	} {
+		if retryable := defaultRetryOnError(tc.err); retryable != tc.retryable {
+			t.Errorf("%s: expected retryable %v, got %v", tc.name, tc.retryable, retryable)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+func fastRetryPolicy() *transmissionrpc.RetryPolicy {
+	return &transmissionrpc.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{RetryPolicy: fastRetryPolicy()})
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusServiceUnavailable})
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusBadGateway})
+	if _, err := client.SessionStats(context.Background()); err != nil {
+		t.Fatalf("expected the call to succeed after retries: %v", err)
+	}
+	if count := countRequests(daemon, "session-stats"); count != 3 {
+		t.Fatalf("expected 3 attempts, got %d", count)
+	}
+	// attempts are capped
+	for i := 0; i < 3; i++ {
+		daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusServiceUnavailable})
+	}
+	_, err := client.SessionStats(context.Background())
+	if !errors.Is(err, transmissionrpc.HTTPStatusCode(http.StatusServiceUnavailable)) {
+		t.Fatalf("expected a 503 error once attempts are exhausted, got %v", err)
+	}
+	if count := countRequests(daemon, "session-stats"); count != 6 {
+		t.Fatalf("expected 3 more attempts, got %d", count-3)
+	}
+}
+
+func TestRetryPolicyNonRetryable(t *testing.T) {
+	daemon := newTestDaemon(t)
+	client := newTestClient(t, daemon, &transmissionrpc.Config{RetryPolicy: fastRetryPolicy()})
+	// status codes not retried by default
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusInternalServerError})
+	if _, err := client.SessionStats(context.Background()); err == nil {
+		t.Fatal("expected the 500 error to be returned")
+	}
+	if count := countRequests(daemon, "session-stats"); count != 1 {
+		t.Fatalf("expected 1 attempt, got %d", count)
+	}
+	// non idempotent methods are not replayed once the daemon received them
+	id := daemon.AddTorrent(map[string]interface{}{"name": "queued"})
+	daemon.AddTorrent(map[string]interface{}{"name": "other"})
+	daemon.FailNext("queue-move-up", transmissionrpctest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if err := client.QueueMoveUp(context.Background(), []int64{id}); err == nil {
+		t.Fatal("expected the 503 error to be returned")
+	}
+	if count := countRequests(daemon, "queue-move-up"); count != 1 {
+		t.Fatalf("expected 1 attempt, got %d", count)
+	}
+	// unless allowed
+	policy := fastRetryPolicy()
+	policy.RetryNonIdempotent = true
+	client = newTestClient(t, daemon, &transmissionrpc.Config{RetryPolicy: policy})
+	daemon.FailNext("queue-move-up", transmissionrpctest.Failure{StatusCode: http.StatusServiceUnavailable})
+	if err := client.QueueMoveUp(context.Background(), []int64{id}); err != nil {
+		t.Fatalf("expected the call to succeed after a retry: %v", err)
+	}
+	if count := countRequests(daemon, "queue-move-up"); count != 3 {
+		t.Fatalf("expected 2 more attempts, got %d", count-1)
+	}
+}
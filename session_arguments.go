@@ -43,6 +43,8 @@ type SessionArguments struct {
 	AltSpeedTimeEnabled              *bool       `json:"alt-speed-time-enabled"`               // true means the scheduled on/off times are used
 	AltSpeedTimeEnd                  *int64      `json:"alt-speed-time-end"`                   // when to turn off alt speeds (units: same)
 	AltSpeedUp                       *int64      `json:"alt-speed-up"`                         // max global upload speed (KBps)
+	AntiBruteForceEnabled            *bool       `json:"anti_brute_force_enabled"`             // RPC v18: true means ban the RPC clients failing to authenticate too many times
+	AntiBruteForceThreshold          *int64      `json:"anti_brute_force_threshold"`           // RPC v18: number of failed authentications before a ban
 	BlocklistEnabled                 *bool       `json:"blocklist-enabled"`                    // true means enabled
 	BlocklistSize                    *int64      `json:"blocklist-size"`                       // number of rules in the blocklist
 	BlocklistURL                     *string     `json:"blocklist-url"`                        // location of the blocklist to use for "blocklist-update"
@@ -234,9 +236,14 @@ type sessionGetParams struct {
 // See the JSON tags of the SessionArguments struct for valid fields.
 // https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#412-accessors
 func (c *Client) SessionArgumentsGet(ctx context.Context, fields []string) (sessionArgs SessionArguments, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	if err = c.validateSessionFields(fields); err != nil {
 		return
 	}
+	if err = c.checkSessionFields(ctx, fields); err != nil {
+		return
+	}
 	if err = c.rpcCall(ctx, "session-get", sessionGetParams{Fields: fields}, &sessionArgs); err != nil {
 		err = fmt.Errorf("'session-get' rpc method failed: %w", err)
 	}
@@ -266,6 +273,8 @@ func (c *Client) validateSessionFields(fields []string) (err error) {
 // SessionArgumentsSet allows to modify global/session values.
 // https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#411-mutators
 func (c *Client) SessionArgumentsSet(ctx context.Context, payload SessionArguments) (err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	// Sanitize fields that can not be set
 	payload.BlocklistSize = nil
 	payload.ConfigDir = nil
@@ -275,9 +284,24 @@ func (c *Client) SessionArgumentsSet(ctx context.Context, payload SessionArgumen
 	payload.SessionID = nil
 	payload.Units = nil
 	payload.Version = nil
+	if err = c.checkSessionFields(ctx, payload.setFields()); err != nil {
+		return
+	}
 	// Exec
 	if err = c.rpcCall(ctx, "session-set", payload, nil); err != nil {
 		err = fmt.Errorf("'session-set' rpc method failed: %w", err)
 	}
 	return
 }
+
+// setFields returns the JSON names of the non nil fields.
+func (sa SessionArguments) setFields() (fields []string) {
+	sav := reflect.ValueOf(sa)
+	sat := sav.Type()
+	for i := 0; i < sav.NumField(); i++ {
+		if !sav.Field(i).IsNil() {
+			fields = append(fields, sat.Field(i).Tag.Get("json"))
+		}
+	}
+	return
+}
@@ -6,6 +6,7 @@ package transmissionrpc
 */
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -26,22 +27,42 @@ func init() {
 
 // TorrentGetAll returns all the known fields for all the torrents.
 func (c *Client) TorrentGetAll(ctx context.Context) (torrents []Torrent, err error) {
-	// Send already validated fields to the low level fx
-	return c.torrentGet(ctx, validTorrentFields, nil)
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	// Send already validated fields (supported by the server) to the low level fx
+	fields, err := c.supportedTorrentFields(ctx, validTorrentFields)
+	if err != nil {
+		return
+	}
+	return c.torrentGet(ctx, fields, nil)
 }
 
 // TorrentGetAllFor returns all known fields for the given torrent's ids.
 func (c *Client) TorrentGetAllFor(ctx context.Context, ids []int64) (torrents []Torrent, err error) {
-	return c.torrentGet(ctx, validTorrentFields, ids)
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	fields, err := c.supportedTorrentFields(ctx, validTorrentFields)
+	if err != nil {
+		return
+	}
+	return c.torrentGet(ctx, fields, ids)
 }
 
 // TorrentGetAllForHashes returns all known fields for the given torrent's ids by string (usually hash).
 func (c *Client) TorrentGetAllForHashes(ctx context.Context, hashes []string) (torrents []Torrent, err error) {
-	return c.torrentGetHash(ctx, validTorrentFields, hashes)
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	fields, err := c.supportedTorrentFields(ctx, validTorrentFields)
+	if err != nil {
+		return
+	}
+	return c.torrentGetHash(ctx, fields, hashes)
 }
 
 // TorrentGet returns the given of fields (mandatory) for each ids (optionnal).
 func (c *Client) TorrentGet(ctx context.Context, fields []string, ids []int64) (torrents []Torrent, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	if err = c.validateTorrentFields(fields); err != nil {
 		return
 	}
@@ -50,12 +71,26 @@ func (c *Client) TorrentGet(ctx context.Context, fields []string, ids []int64) (
 
 // TorrentGetHashes returns the given of fields (mandatory) for each ids (optionnal).
 func (c *Client) TorrentGetHashes(ctx context.Context, fields []string, hashes []string) (torrents []Torrent, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	if err = c.validateTorrentFields(fields); err != nil {
 		return
 	}
 	return c.torrentGetHash(ctx, fields, hashes)
 }
 
+// TorrentGetRecentlyActive returns the given fields (mandatory) for the torrents that changed recently
+// (within the last minute) along with the ids of the torrents that have been removed in the same time frame.
+// This allows cheap incremental refreshes on daemons with a lot of torrents.
+func (c *Client) TorrentGetRecentlyActive(ctx context.Context, fields []string) (torrents []Torrent, removed []int64, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	if err = c.validateTorrentFields(fields); err != nil {
+		return
+	}
+	return c.torrentGetRecentlyActive(ctx, fields)
+}
+
 func (c *Client) validateTorrentFields(fields []string) (err error) {
 	// Validate fields
 	var fieldInvalid bool
@@ -77,43 +112,211 @@ func (c *Client) validateTorrentFields(fields []string) (err error) {
 }
 
 func (c *Client) torrentGet(ctx context.Context, fields []string, ids []int64) (torrents []Torrent, err error) {
-	var result torrentGetResults
-	if err = c.rpcCall(ctx, "torrent-get", &torrentGetParams{
-		Fields: fields,
-		IDs:    ids,
-	}, &result); err != nil {
-		err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+	fields, emulated, err := c.prepareTorrentFields(ctx, fields)
+	if err != nil {
 		return
 	}
-	torrents = result.Torrents
+	format, err := c.torrentGetFormat(ctx)
+	if err != nil {
+		return
+	}
+	torrents, _, err = c.torrentGetRequest(ctx, &torrentGetParams{
+		Fields: fields,
+		IDs:    ids,
+		Format: format,
+	}, emulated)
 	return
 }
 
 func (c *Client) torrentGetHash(ctx context.Context, fields []string, hashes []string) (torrents []Torrent, err error) {
-	var result torrentGetResults
-	if err = c.rpcCall(ctx, "torrent-get", &torrentGetHashParams{
+	fields, emulated, err := c.prepareTorrentFields(ctx, fields)
+	if err != nil {
+		return
+	}
+	format, err := c.torrentGetFormat(ctx)
+	if err != nil {
+		return
+	}
+	torrents, _, err = c.torrentGetRequest(ctx, &torrentGetHashParams{
 		Fields: fields,
 		Hashes: hashes,
-	}, &result); err != nil {
-		err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+		Format: format,
+	}, emulated)
+	return
+}
+
+func (c *Client) torrentGetRecentlyActive(ctx context.Context, fields []string) (torrents []Torrent, removed []int64, err error) {
+	fields, emulated, err := c.prepareTorrentFields(ctx, fields)
+	if err != nil {
+		return
+	}
+	format, err := c.torrentGetFormat(ctx)
+	if err != nil {
 		return
 	}
-	torrents = result.Torrents
+	return c.torrentGetRequest(ctx, &torrentGetRecentlyActiveParams{
+		Fields: fields,
+		IDs:    "recently-active",
+		Format: format,
+	}, emulated)
+}
+
+func (c *Client) torrentGetRequest(ctx context.Context, params torrentGetFormatter, emulated []string) (torrents []Torrent, removed []int64, err error) {
+	if params.format() == torrentGetFormatTable {
+		var result torrentGetTableResults
+		if err = c.rpcCall(ctx, "torrent-get", params, &result); err != nil {
+			err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+			return
+		}
+		if torrents, err = decodeTorrentTable(result.Torrents); err != nil {
+			err = fmt.Errorf("'torrent-get' rpc method failed: can't decode table format: %w", err)
+			return
+		}
+		removed = result.Removed
+	} else {
+		var result torrentGetResults
+		if err = c.rpcCall(ctx, "torrent-get", params, &result); err != nil {
+			err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+			return
+		}
+		torrents = result.Torrents
+		removed = result.Removed
+	}
+	if len(emulated) > 0 {
+		for index := range torrents {
+			emulateTorrentFields(&torrents[index], emulated)
+		}
+	}
 	return
 }
 
+// torrentGetFormat returns the torrent-get format to use: the table one if it is enabled (by the
+// TableFormat() call option or else the client Config) and supported by the daemon, the default
+// objects one otherwise.
+func (c *Client) torrentGetFormat(ctx context.Context) (format string, err error) {
+	enabled := c.tableFormat
+	if override := callOptionsFromContext(ctx).tableFormat; override != nil {
+		enabled = *override
+	}
+	if !enabled {
+		return
+	}
+	if !c.noFeatureGating {
+		var version ServerVersion
+		if version, err = c.ServerVersion(ctx); err != nil {
+			err = fmt.Errorf("can't check if the table format is supported by the server: %w", err)
+			return
+		}
+		if !version.Supports(tableFormatRPCVersion) {
+			return
+		}
+	}
+	return torrentGetFormatTable, nil
+}
+
+// torrentGetFormatter is implemented by the torrent-get params to expose their format.
+type torrentGetFormatter interface {
+	format() string
+}
+
+const (
+	torrentGetFormatTable = "table"
+)
+
 type torrentGetParams struct {
 	Fields []string `json:"fields"`
 	IDs    []int64  `json:"ids,omitempty"`
+	Format string   `json:"format,omitempty"`
+}
+
+type torrentGetRecentlyActiveParams struct {
+	Fields []string `json:"fields"`
+	IDs    string   `json:"ids"`
+	Format string   `json:"format,omitempty"`
 }
 
 type torrentGetHashParams struct {
 	Fields []string `json:"fields"`
 	Hashes []string `json:"ids,omitempty"`
+	Format string   `json:"format,omitempty"`
+}
+
+func (params *torrentGetParams) format() string {
+	return params.Format
+}
+
+func (params *torrentGetRecentlyActiveParams) format() string {
+	return params.Format
+}
+
+func (params *torrentGetHashParams) format() string {
+	return params.Format
 }
 
 type torrentGetResults struct {
 	Torrents []Torrent `json:"torrents"`
+	Removed  []int64   `json:"removed"` // only set when "recently-active" is used as ids
+}
+
+type torrentGetTableResults struct {
+	Torrents [][]json.RawMessage `json:"torrents"` // first row contains the fields names
+	Removed  []int64             `json:"removed"`  // only set when "recently-active" is used as ids
+}
+
+// decodeTorrentTable converts the table format rows back to their object form in order to
+// unmarshal them within regular Torrent values (and benefit from the Torrent unmarshaller conversions).
+func decodeTorrentTable(rows [][]json.RawMessage) (torrents []Torrent, err error) {
+	if len(rows) == 0 {
+		return
+	}
+	header, err := decodeTorrentTableHeader(rows[0])
+	if err != nil {
+		return
+	}
+	torrents = make([]Torrent, len(rows)-1)
+	var buffer bytes.Buffer
+	for rowIndex, row := range rows[1:] {
+		if err = decodeTorrentTableRow(header, row, &torrents[rowIndex], &buffer); err != nil {
+			err = fmt.Errorf("can't decode row #%d: %w", rowIndex, err)
+			return
+		}
+	}
+	return
+}
+
+// decodeTorrentTableHeader returns the header fields names already JSON encoded.
+func decodeTorrentTableHeader(row []json.RawMessage) (header [][]byte, err error) {
+	header = make([][]byte, len(row))
+	var fieldName string
+	for index, rawFieldName := range row {
+		if err = json.Unmarshal(rawFieldName, &fieldName); err != nil {
+			err = fmt.Errorf("can't decode header field name #%d: %w", index, err)
+			return
+		}
+		if header[index], err = json.Marshal(fieldName); err != nil {
+			err = fmt.Errorf("can't encode header field name '%s': %w", fieldName, err)
+			return
+		}
+	}
+	return
+}
+
+func decodeTorrentTableRow(header [][]byte, row []json.RawMessage, torrent *Torrent, buffer *bytes.Buffer) (err error) {
+	if len(row) != len(header) {
+		return fmt.Errorf("row has %d values while header has %d fields", len(row), len(header))
+	}
+	buffer.Reset()
+	buffer.WriteByte('{')
+	for index, value := range row {
+		if index > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(header[index])
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return json.Unmarshal(buffer.Bytes(), torrent)
 }
 
 // Torrent represents all the possible fields of data for a torrent.
@@ -123,6 +326,7 @@ type Torrent struct {
 	AddedDate               *time.Time        `json:"addedDate"`
 	Availability            []int64           `json:"availability"` // RPC v17
 	BandwidthPriority       *int64            `json:"bandwidthPriority"`
+	BytesCompleted          []cunits.Bits     `json:"bytes_completed"` // RPC v18
 	Comment                 *string           `json:"comment"`
 	CorruptEver             *int64            `json:"corruptEver"`
 	Creator                 *string           `json:"creator"`
@@ -167,7 +371,7 @@ type Torrent struct {
 	PercentDone             *float64          `json:"percentDone"`
 	Pieces                  *string           `json:"pieces"`
 	PieceCount              *int64            `json:"pieceCount"`
-	PieceSize               *cunits.Bits      `json:"PieceSize"`
+	PieceSize               *cunits.Bits      `json:"pieceSize"`
 	Priorities              []int64           `json:"priorities"`
 	PrimaryMimeType         *string           `json:"primary-mime-type"` // RPC v17
 	QueuePosition           *int64            `json:"queuePosition"`
@@ -180,6 +384,7 @@ type Torrent struct {
 	SeedIdleMode            *int64            `json:"seedIdleMode"`
 	SeedRatioLimit          *float64          `json:"seedRatioLimit"`
 	SeedRatioMode           *SeedRatioMode    `json:"seedRatioMode"`
+	SequentialDownload      *bool             `json:"sequential_download"` // RPC v18
 	SizeWhenDone            *cunits.Bits      `json:"sizeWhenDone"`
 	StartDate               *time.Time        `json:"startDate"`
 	Status                  *TorrentStatus    `json:"status"`
@@ -220,6 +425,7 @@ func (t *Torrent) UnmarshalJSON(data []byte) (err error) {
 	tmp := &struct {
 		ActivityDate       *int64  `json:"activityDate"`
 		AddedDate          *int64  `json:"addedDate"`
+		BytesCompleted     []int64 `json:"bytes_completed"`
 		DateCreated        *int64  `json:"dateCreated"`
 		DoneDate           *int64  `json:"doneDate"`
 		EditDate           *int64  `json:"editDate"`
@@ -248,6 +454,12 @@ func (t *Torrent) UnmarshalJSON(data []byte) (err error) {
 		ad := time.Unix(*tmp.AddedDate, 0)
 		t.AddedDate = &ad
 	}
+	if tmp.BytesCompleted != nil {
+		t.BytesCompleted = make([]cunits.Bits, len(tmp.BytesCompleted))
+		for index, value := range tmp.BytesCompleted {
+			t.BytesCompleted[index] = cunits.ImportInByte(float64(value))
+		}
+	}
 	if tmp.DateCreated != nil {
 		dc := time.Unix(*tmp.DateCreated, 0)
 		t.DateCreated = &dc
@@ -309,6 +521,7 @@ func (t Torrent) MarshalJSON() (data []byte, err error) {
 	tmp := &struct {
 		ActivityDate       *int64  `json:"activityDate"`
 		AddedDate          *int64  `json:"addedDate"`
+		BytesCompleted     []int64 `json:"bytes_completed"`
 		DateCreated        *int64  `json:"dateCreated"`
 		DoneDate           *int64  `json:"doneDate"`
 		SecondsDownloading *int64  `json:"secondsDownloading"`
@@ -353,6 +566,13 @@ func (t Torrent) MarshalJSON() (data []byte, err error) {
 		st := t.StartDate.Unix()
 		tmp.StartDate = &st
 	}
+	// Sizes as bytes
+	if t.BytesCompleted != nil {
+		tmp.BytesCompleted = make([]int64, len(t.BytesCompleted))
+		for index, value := range t.BytesCompleted {
+			tmp.BytesCompleted[index] = int64(value.Byte())
+		}
+	}
 	// Boolean as number
 	if t.Wanted != nil {
 		tmp.Wanted = make([]int64, len(t.Wanted))
@@ -368,7 +588,9 @@ func (t Torrent) MarshalJSON() (data []byte, err error) {
 
 // TorrentFile represent one file from a Torrent.
 type TorrentFile struct {
+	BeginPiece     int64  `json:"begin_piece"` // RPC v18
 	BytesCompleted int64  `json:"bytesCompleted"`
+	EndPiece       int64  `json:"end_piece"` // RPC v18
 	Length         int64  `json:"length"`
 	Name           string `json:"name"`
 }
@@ -539,10 +761,13 @@ type Tracker struct {
 }
 
 // TrackerStats represent the extended data of a torrent's tracker.
+// Its RPC v18 fields are not gated as the trackerStats torrent field is available on all the daemons:
+// the older ones leave them to their zero value.
 type TrackerStats struct {
 	Announce              string    `json:"announce"`
 	AnnounceState         int64     `json:"announceState"`
 	DownloadCount         int64     `json:"downloadCount"`
+	DownloaderCount       int64     `json:"downloader_count"` // RPC v18
 	HasAnnounced          bool      `json:"hasAnnounced"`
 	HasScraped            bool      `json:"hasScraped"`
 	Host                  string    `json:"host"`
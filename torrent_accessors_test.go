@@ -0,0 +1,207 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+// lastTorrentGetFormat returns the format of the last torrent-get request received by daemon.
+func lastTorrentGetFormat(t *testing.T, daemon *transmissionrpctest.Server) string {
+	t.Helper()
+	requests := daemon.Requests()
+	for index := len(requests) - 1; index >= 0; index-- {
+		if requests[index].Method != "torrent-get" {
+			continue
+		}
+		var arguments struct {
+			Format string `json:"format"`
+		}
+		if err := json.Unmarshal(requests[index].Arguments, &arguments); err != nil {
+			t.Fatalf("can't decode torrent-get arguments: %v", err)
+		}
+		return arguments.Format
+	}
+	t.Fatal("no torrent-get request")
+	return ""
+}
+
+func TestTorrentGetRecentlyActive(t *testing.T) {
+	daemon := newStubDaemon(t)
+	daemon.handle("torrent-get", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"torrents": []map[string]interface{}{{"id": 1, "name": "kept"}},
+			"removed":  []int64{2, 3},
+		}, nil
+	})
+	client := daemon.client(t, nil)
+	torrents, removed, err := client.TorrentGetRecentlyActive(context.Background(), []string{"id", "name"})
+	if err != nil {
+		t.Fatalf("torrent-get failed: %v", err)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); arguments != `{"fields":["id","name"],"ids":"recently-active"}` {
+		t.Fatalf("unexpected torrent-get arguments: %s", arguments)
+	}
+	if len(torrents) != 1 || *torrents[0].ID != 1 || *torrents[0].Name != "kept" {
+		t.Fatalf("expected the kept torrent only, got %+v", torrents)
+	}
+	if len(removed) != 2 || removed[0] != 2 || removed[1] != 3 {
+		t.Fatalf("expected the removed torrents ids, got %v", removed)
+	}
+	if _, _, err = client.TorrentGetRecentlyActive(context.Background(), []string{"nope"}); err == nil {
+		t.Fatal("expected an invalid field to be rejected")
+	}
+}
+
+func TestTableFormatDecodesLikeObjects(t *testing.T) {
+	daemon := newStubDaemon(t)
+	daemon.handleTorrents(
+		map[string]interface{}{
+			"id":          1,
+			"name":        "ubuntu.iso",
+			"labels":      []interface{}{"linux", "iso"},
+			"percentDone": 0.25,
+			"totalSize":   4 << 30,
+			"addedDate":   1700000000,
+			"files": []interface{}{
+				map[string]interface{}{"name": "ubuntu.iso", "length": 4 << 30, "bytesCompleted": 1 << 30},
+			},
+		},
+		map[string]interface{}{"id": 2, "name": "debian.iso", "error": 3, "errorString": "no data found"},
+	)
+	objects, err := daemon.client(t, nil).TorrentGetAll(context.Background())
+	if err != nil {
+		t.Fatalf("objects torrent-get failed: %v", err)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); strings.Contains(arguments, `"format"`) {
+		t.Fatalf("unexpected format requested: %s", arguments)
+	}
+	table, err := daemon.client(t, &transmissionrpc.Config{TableFormat: true}).TorrentGetAll(context.Background())
+	if err != nil {
+		t.Fatalf("table torrent-get failed: %v", err)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); !strings.Contains(arguments, `"format":"table"`) {
+		t.Fatalf("table format not requested: %s", arguments)
+	}
+	if len(objects) != 2 || len(objects[0].Files) != 1 || len(objects[0].Labels) != 2 {
+		t.Fatalf("unexpected torrents: %+v", objects)
+	}
+	if !reflect.DeepEqual(objects, table) {
+		t.Fatalf("table format decoding differs:\n%+v\n%+v", objects, table)
+	}
+}
+
+func TestTableFormatInvalidHeader(t *testing.T) {
+	daemon := newStubDaemon(t)
+	daemon.handle("torrent-get", func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"torrents": []interface{}{
+			[]interface{}{"id", 42},
+			[]interface{}{1, "name"},
+		}}, nil
+	})
+	if _, err := daemon.client(t, &transmissionrpc.Config{TableFormat: true}).TorrentGetAll(context.Background()); err == nil {
+		t.Fatal("expected an invalid table header to be rejected")
+	}
+}
+
+func TestTransmission41Fields(t *testing.T) {
+	daemon := newTestDaemon(t)
+	id := daemon.AddTorrent(map[string]interface{}{
+		"name":                "sequential",
+		"sequential_download": true,
+		"bytes_completed":     []interface{}{1024, 0},
+		"trackerStats":        []interface{}{map[string]interface{}{"id": 1, "downloader_count": 3, "lastScrapeTimedOut": false}},
+	})
+	client := newTestClient(t, daemon, nil)
+	ctx := context.Background()
+	fields := []string{"id", "sequential_download", "bytes_completed"}
+	// trackerStats is not gated, only its RPC v18 members are missing on older daemons
+	if _, err := client.TorrentGet(ctx, []string{"id", "trackerStats"}, nil); err != nil {
+		t.Fatalf("can't get tracker stats on RPC v17: %v", err)
+	}
+	enabled := true
+	// RPC v18 fields
+	if _, err := client.TorrentGet(ctx, fields, nil); !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer on RPC v17, got %v", err)
+	}
+	if err := client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{AntiBruteForceEnabled: &enabled}); !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer on RPC v17, got %v", err)
+	}
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 18})
+	client = newTestClient(t, daemon, nil)
+	torrents, err := client.TorrentGet(ctx, fields, []int64{id})
+	if err != nil {
+		t.Fatalf("can't get RPC v18 fields: %v", err)
+	}
+	torrent := torrents[0]
+	if torrent.SequentialDownload == nil || !*torrent.SequentialDownload {
+		t.Fatalf("expected sequential download to be enabled, got %v", torrent.SequentialDownload)
+	}
+	if len(torrent.BytesCompleted) != 2 || torrent.BytesCompleted[0].Byte() != 1024 || torrent.BytesCompleted[1].Byte() != 0 {
+		t.Fatalf("unexpected bytes completed: %v", torrent.BytesCompleted)
+	}
+	if torrents, err = client.TorrentGet(ctx, []string{"id", "trackerStats"}, []int64{id}); err != nil {
+		t.Fatalf("can't get tracker stats: %v", err)
+	}
+	if stats := torrents[0].TrackerStats; len(stats) != 1 || stats[0].DownloaderCount != 3 {
+		t.Fatalf("unexpected tracker stats: %+v", stats)
+	}
+	disabled := false
+	if err = client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{IDs: []int64{id}, SequentialDownload: &disabled}); err != nil {
+		t.Fatalf("can't disable sequential download: %v", err)
+	}
+	if fields, _ := daemon.Torrent(id); fields["sequential_download"] != false {
+		t.Fatalf("expected sequential download to be disabled, got %v", fields["sequential_download"])
+	}
+	if err = client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{AntiBruteForceEnabled: &enabled}); err != nil {
+		t.Fatalf("can't enable anti brute force: %v", err)
+	}
+}
+
+func TestTableFormatGating(t *testing.T) {
+	for _, tc := range []struct {
+		rpcVersion int
+		format     string
+	}{
+		{rpcVersion: 17, format: "table"},
+		{rpcVersion: 15, format: ""},
+	} {
+		daemon := newTestDaemon(t)
+		daemon.UpdateSession(map[string]interface{}{"rpc-version": tc.rpcVersion})
+		id := daemon.AddTorrent(map[string]interface{}{"name": "debian.iso"})
+		client := newTestClient(t, daemon, &transmissionrpc.Config{TableFormat: true})
+		// accessors
+		torrents, err := client.TorrentGet(context.Background(), []string{"id", "name"}, nil)
+		if err != nil {
+			t.Fatalf("RPC v%d: torrent-get failed: %v", tc.rpcVersion, err)
+		}
+		if len(torrents) != 1 || *torrents[0].ID != id || *torrents[0].Name != "debian.iso" {
+			t.Fatalf("RPC v%d: unexpected torrents: %+v", tc.rpcVersion, torrents)
+		}
+		if format := lastTorrentGetFormat(t, daemon); format != tc.format {
+			t.Fatalf("RPC v%d: expected format %q, got %q", tc.rpcVersion, tc.format, format)
+		}
+		// iterator
+		it, err := client.TorrentGetIterator(context.Background(), []string{"id", "name"}, nil)
+		if err != nil {
+			t.Fatalf("RPC v%d: iterator failed: %v", tc.rpcVersion, err)
+		}
+		var names []string
+		for it.Next() {
+			names = append(names, *it.Torrent().Name)
+		}
+		it.Close()
+		if err = it.Err(); err != nil || len(names) != 1 || names[0] != "debian.iso" {
+			t.Fatalf("RPC v%d: unexpected iteration: %v (err: %v)", tc.rpcVersion, names, err)
+		}
+		if format := lastTorrentGetFormat(t, daemon); format != tc.format {
+			t.Fatalf("RPC v%d: expected iterator format %q, got %q", tc.rpcVersion, tc.format, format)
+		}
+	}
+}
@@ -151,3 +151,39 @@ func (c *Client) TorrentReannounceRecentlyActive(ctx context.Context) (err error
 	}
 	return
 }
+
+// TorrentStartFor starts the torrent(s) targeted by the selector.
+func (c *Client) TorrentStartFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.torrentAction(ctx, "torrent-start", selector)
+}
+
+// TorrentStartNowFor starts (now) the torrent(s) targeted by the selector.
+func (c *Client) TorrentStartNowFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.torrentAction(ctx, "torrent-start-now", selector)
+}
+
+// TorrentStopFor stops the torrent(s) targeted by the selector.
+func (c *Client) TorrentStopFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.torrentAction(ctx, "torrent-stop", selector)
+}
+
+// TorrentVerifyFor verifies the torrent(s) targeted by the selector.
+func (c *Client) TorrentVerifyFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.torrentAction(ctx, "torrent-verify", selector)
+}
+
+// TorrentReannounceFor reannounces the torrent(s) targeted by the selector.
+func (c *Client) TorrentReannounceFor(ctx context.Context, selector IDSelector) (err error) {
+	return c.torrentAction(ctx, "torrent-reannounce", selector)
+}
+
+func (c *Client) torrentAction(ctx context.Context, method string, selector IDSelector) (err error) {
+	ids, err := selector.payload()
+	if err != nil {
+		return
+	}
+	if err = c.rpcCall(ctx, method, &torrentSelectorParam{IDs: ids}, nil); err != nil {
+		err = fmt.Errorf("'%s' rpc method failed: %w", method, err)
+	}
+	return
+}
@@ -63,11 +63,16 @@ func (c *Client) TorrentAddFile(ctx context.Context, filepath string) (torrent T
 // TorrentAdd allows to send an Add payload. If successful (torrent added or duplicate) torrent
 // return value will only have HashString, ID and Name fields set up.
 func (c *Client) TorrentAdd(ctx context.Context, payload TorrentAddPayload) (torrent Torrent, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	// Validate
 	if payload.Filename == nil && payload.MetaInfo == nil {
 		err = errors.New("fields Filename and MetaInfo can't be both nil")
 		return
 	}
+	if err = c.checkTorrentAddArguments(ctx, payload.setArguments()); err != nil {
+		return
+	}
 	// Send payload
 	var result torrentAddAnswer
 	if err = c.rpcCall(ctx, "torrent-add", payload, &result); err != nil {
@@ -87,19 +92,20 @@ func (c *Client) TorrentAdd(ctx context.Context, payload TorrentAddPayload) (tor
 
 // TorrentAddPayload represents the data to send in order to add a torrent.
 type TorrentAddPayload struct {
-	Cookies           *string  `json:"cookies"`           // pointer to a string of one or more cookies
-	DownloadDir       *string  `json:"download-dir"`      // path to download the torrent to
-	Filename          *string  `json:"filename"`          // filename or URL of the .torrent file
-	Labels            []string `json:"labels"`            // Labels for the torrent
-	MetaInfo          *string  `json:"metainfo"`          // base64-encoded .torrent content
-	Paused            *bool    `json:"paused"`            // if true, don't start the torrent
-	PeerLimit         *int64   `json:"peer-limit"`        // maximum number of peers
-	BandwidthPriority *int64   `json:"bandwidthPriority"` // torrent's bandwidth tr_priority_t
-	FilesWanted       []int64  `json:"files-wanted"`      // indices of file(s) to download
-	FilesUnwanted     []int64  `json:"files-unwanted"`    // indices of file(s) to not download
-	PriorityHigh      []int64  `json:"priority-high"`     // indices of high-priority file(s)
-	PriorityLow       []int64  `json:"priority-low"`      // indices of low-priority file(s)
-	PriorityNormal    []int64  `json:"priority-normal"`   // indices of normal-priority file(s)
+	Cookies            *string  `json:"cookies"`             // pointer to a string of one or more cookies
+	DownloadDir        *string  `json:"download-dir"`        // path to download the torrent to
+	Filename           *string  `json:"filename"`            // filename or URL of the .torrent file
+	Labels             []string `json:"labels"`              // Labels for the torrent
+	MetaInfo           *string  `json:"metainfo"`            // base64-encoded .torrent content
+	Paused             *bool    `json:"paused"`              // if true, don't start the torrent
+	PeerLimit          *int64   `json:"peer-limit"`          // maximum number of peers
+	BandwidthPriority  *int64   `json:"bandwidthPriority"`   // torrent's bandwidth tr_priority_t
+	FilesWanted        []int64  `json:"files-wanted"`        // indices of file(s) to download
+	FilesUnwanted      []int64  `json:"files-unwanted"`      // indices of file(s) to not download
+	PriorityHigh       []int64  `json:"priority-high"`       // indices of high-priority file(s)
+	PriorityLow        []int64  `json:"priority-low"`        // indices of low-priority file(s)
+	PriorityNormal     []int64  `json:"priority-normal"`     // indices of normal-priority file(s)
+	SequentialDownload *bool    `json:"sequential_download"` // RPC v18: download the pieces in order
 }
 
 // MarshalJSON allows to marshall into JSON only the non nil fields.
@@ -123,6 +129,18 @@ func (tap TorrentAddPayload) MarshalJSON() (data []byte, err error) {
 	return json.Marshal(cleanPayload)
 }
 
+// setArguments returns the JSON names of the non nil fields.
+func (tap TorrentAddPayload) setArguments() (arguments []string) {
+	tapv := reflect.ValueOf(tap)
+	tapt := tapv.Type()
+	for i := 0; i < tapv.NumField(); i++ {
+		if !tapv.Field(i).IsNil() {
+			arguments = append(arguments, tapt.Field(i).Tag.Get("json"))
+		}
+	}
+	return
+}
+
 type torrentAddAnswer struct {
 	TorrentAdded     *Torrent `json:"torrent-added"`
 	TorrentDuplicate *Torrent `json:"torrent-duplicate"`
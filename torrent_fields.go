@@ -0,0 +1,215 @@
+package transmissionrpc
+
+import (
+	"context"
+)
+
+/*
+	Torrent Fields
+	Typed alternative to the raw string fields names accepted by the torrent-get accessors.
+*/
+
+// TorrentField is the name of a Torrent field as known by the torrent-get RPC method.
+// Use the TorrentFieldXXX constants to benefit from compile time checks.
+type TorrentField string
+
+// All the fields of a Torrent, named after their Torrent struct field.
+const (
+	TorrentFieldActivityDate            TorrentField = "activityDate"
+	TorrentFieldAddedDate               TorrentField = "addedDate"
+	TorrentFieldAvailability            TorrentField = "availability"
+	TorrentFieldBandwidthPriority       TorrentField = "bandwidthPriority"
+	TorrentFieldBytesCompleted          TorrentField = "bytes_completed"
+	TorrentFieldComment                 TorrentField = "comment"
+	TorrentFieldCorruptEver             TorrentField = "corruptEver"
+	TorrentFieldCreator                 TorrentField = "creator"
+	TorrentFieldDateCreated             TorrentField = "dateCreated"
+	TorrentFieldDesiredAvailable        TorrentField = "desiredAvailable"
+	TorrentFieldDoneDate                TorrentField = "doneDate"
+	TorrentFieldDownloadDir             TorrentField = "downloadDir"
+	TorrentFieldDownloadedEver          TorrentField = "downloadedEver"
+	TorrentFieldDownloadLimit           TorrentField = "downloadLimit"
+	TorrentFieldDownloadLimited         TorrentField = "downloadLimited"
+	TorrentFieldEditDate                TorrentField = "editDate"
+	TorrentFieldError                   TorrentField = "error"
+	TorrentFieldErrorString             TorrentField = "errorString"
+	TorrentFieldETA                     TorrentField = "eta"
+	TorrentFieldETAIdle                 TorrentField = "etaIdle"
+	TorrentFieldFileCount               TorrentField = "file-count"
+	TorrentFieldFiles                   TorrentField = "files"
+	TorrentFieldFileStats               TorrentField = "fileStats"
+	TorrentFieldGroup                   TorrentField = "group"
+	TorrentFieldHashString              TorrentField = "hashString"
+	TorrentFieldHaveUnchecked           TorrentField = "haveUnchecked"
+	TorrentFieldHaveValid               TorrentField = "haveValid"
+	TorrentFieldHonorsSessionLimits     TorrentField = "honorsSessionLimits"
+	TorrentFieldID                      TorrentField = "id"
+	TorrentFieldIsFinished              TorrentField = "isFinished"
+	TorrentFieldIsPrivate               TorrentField = "isPrivate"
+	TorrentFieldIsStalled               TorrentField = "isStalled"
+	TorrentFieldLabels                  TorrentField = "labels"
+	TorrentFieldLeftUntilDone           TorrentField = "leftUntilDone"
+	TorrentFieldMagnetLink              TorrentField = "magnetLink"
+	TorrentFieldManualAnnounceTime      TorrentField = "manualAnnounceTime"
+	TorrentFieldMaxConnectedPeers       TorrentField = "maxConnectedPeers"
+	TorrentFieldMetadataPercentComplete TorrentField = "metadataPercentComplete"
+	TorrentFieldName                    TorrentField = "name"
+	TorrentFieldPeerLimit               TorrentField = "peer-limit"
+	TorrentFieldPeers                   TorrentField = "peers"
+	TorrentFieldPeersConnected          TorrentField = "peersConnected"
+	TorrentFieldPeersFrom               TorrentField = "peersFrom"
+	TorrentFieldPeersGettingFromUs      TorrentField = "peersGettingFromUs"
+	TorrentFieldPeersSendingToUs        TorrentField = "peersSendingToUs"
+	TorrentFieldPercentComplete         TorrentField = "percentComplete"
+	TorrentFieldPercentDone             TorrentField = "percentDone"
+	TorrentFieldPieces                  TorrentField = "pieces"
+	TorrentFieldPieceCount              TorrentField = "pieceCount"
+	TorrentFieldPieceSize               TorrentField = "pieceSize"
+	TorrentFieldPriorities              TorrentField = "priorities"
+	TorrentFieldPrimaryMimeType         TorrentField = "primary-mime-type"
+	TorrentFieldQueuePosition           TorrentField = "queuePosition"
+	TorrentFieldRateDownload            TorrentField = "rateDownload"
+	TorrentFieldRateUpload              TorrentField = "rateUpload"
+	TorrentFieldRecheckProgress         TorrentField = "recheckProgress"
+	TorrentFieldTimeDownloading         TorrentField = "secondsDownloading"
+	TorrentFieldTimeSeeding             TorrentField = "secondsSeeding"
+	TorrentFieldSeedIdleLimit           TorrentField = "seedIdleLimit"
+	TorrentFieldSeedIdleMode            TorrentField = "seedIdleMode"
+	TorrentFieldSeedRatioLimit          TorrentField = "seedRatioLimit"
+	TorrentFieldSeedRatioMode           TorrentField = "seedRatioMode"
+	TorrentFieldSequentialDownload      TorrentField = "sequential_download"
+	TorrentFieldSizeWhenDone            TorrentField = "sizeWhenDone"
+	TorrentFieldStartDate               TorrentField = "startDate"
+	TorrentFieldStatus                  TorrentField = "status"
+	TorrentFieldTrackers                TorrentField = "trackers"
+	TorrentFieldTrackerList             TorrentField = "trackerList"
+	TorrentFieldTrackerStats            TorrentField = "trackerStats"
+	TorrentFieldTotalSize               TorrentField = "totalSize"
+	TorrentFieldTorrentFile             TorrentField = "torrentFile"
+	TorrentFieldUploadedEver            TorrentField = "uploadedEver"
+	TorrentFieldUploadLimit             TorrentField = "uploadLimit"
+	TorrentFieldUploadLimited           TorrentField = "uploadLimited"
+	TorrentFieldUploadRatio             TorrentField = "uploadRatio"
+	TorrentFieldWanted                  TorrentField = "wanted"
+	TorrentFieldWebSeeds                TorrentField = "webseeds"
+	TorrentFieldWebSeedsSendingToUs     TorrentField = "webseedsSendingToUs"
+)
+
+// TorrentFields is a set of torrent fields.
+type TorrentFields []TorrentField
+
+// Strings returns the fields names as accepted by the string based torrent-get accessors.
+func (tf TorrentFields) Strings() (fields []string) {
+	fields = make([]string, len(tf))
+	for index, field := range tf {
+		fields[index] = string(field)
+	}
+	return
+}
+
+// With returns a new set containing the current fields and the additional ones (duplicates are skipped).
+func (tf TorrentFields) With(fields ...TorrentField) (merged TorrentFields) {
+	merged = make(TorrentFields, len(tf), len(tf)+len(fields))
+	copy(merged, tf)
+	var known bool
+	for _, field := range fields {
+		known = false
+		for _, existing := range merged {
+			if field == existing {
+				known = true
+				break
+			}
+		}
+		if !known {
+			merged = append(merged, field)
+		}
+	}
+	return
+}
+
+var (
+	// TorrentFieldsListView contains the fields usually needed to display a list of torrents.
+	// Like the other presets, it only contains fields supported by all the daemons: the newer ones
+	// (TorrentFieldLabels for example) can be added with With().
+	TorrentFieldsListView = TorrentFields{
+		TorrentFieldID,
+		TorrentFieldHashString,
+		TorrentFieldName,
+		TorrentFieldStatus,
+		TorrentFieldError,
+		TorrentFieldErrorString,
+		TorrentFieldPercentDone,
+		TorrentFieldSizeWhenDone,
+		TorrentFieldLeftUntilDone,
+		TorrentFieldRateDownload,
+		TorrentFieldRateUpload,
+		TorrentFieldETA,
+		TorrentFieldUploadRatio,
+		TorrentFieldPeersConnected,
+		TorrentFieldQueuePosition,
+		TorrentFieldAddedDate,
+	}
+	// TorrentFieldsDetailView contains the fields usually needed to display the details of a torrent.
+	TorrentFieldsDetailView = TorrentFieldsListView.With(
+		TorrentFieldActivityDate,
+		TorrentFieldComment,
+		TorrentFieldCorruptEver,
+		TorrentFieldCreator,
+		TorrentFieldDateCreated,
+		TorrentFieldDoneDate,
+		TorrentFieldDownloadDir,
+		TorrentFieldDownloadedEver,
+		TorrentFieldFiles,
+		TorrentFieldFileStats,
+		TorrentFieldHaveUnchecked,
+		TorrentFieldHaveValid,
+		TorrentFieldIsPrivate,
+		TorrentFieldMagnetLink,
+		TorrentFieldPieceCount,
+		TorrentFieldPieceSize,
+		TorrentFieldPriorities,
+		TorrentFieldSeedIdleLimit,
+		TorrentFieldSeedIdleMode,
+		TorrentFieldSeedRatioLimit,
+		TorrentFieldSeedRatioMode,
+		TorrentFieldTotalSize,
+		TorrentFieldTrackerStats,
+		TorrentFieldUploadedEver,
+		TorrentFieldWanted,
+	)
+	// TorrentFieldsPeerView contains the fields usually needed to display the peers of a torrent.
+	TorrentFieldsPeerView = TorrentFields{
+		TorrentFieldID,
+		TorrentFieldHashString,
+		TorrentFieldName,
+		TorrentFieldPeers,
+		TorrentFieldPeersConnected,
+		TorrentFieldPeersFrom,
+		TorrentFieldPeersGettingFromUs,
+		TorrentFieldPeersSendingToUs,
+		TorrentFieldWebSeedsSendingToUs,
+		TorrentFieldRateDownload,
+		TorrentFieldRateUpload,
+	}
+)
+
+// TorrentGetFields returns the given typed fields (mandatory) for each ids (optionnal).
+func (c *Client) TorrentGetFields(ctx context.Context, fields TorrentFields, ids []int64) (torrents []Torrent, err error) {
+	return c.TorrentGet(ctx, fields.Strings(), ids)
+}
+
+// TorrentGetHashesFields returns the given typed fields (mandatory) for each hashes (optionnal).
+func (c *Client) TorrentGetHashesFields(ctx context.Context, fields TorrentFields, hashes []string) (torrents []Torrent, err error) {
+	return c.TorrentGetHashes(ctx, fields.Strings(), hashes)
+}
+
+// TorrentGetRecentlyActiveFields returns the given typed fields (mandatory) for the recently active torrents
+// along with the ids of the recently removed torrents.
+func (c *Client) TorrentGetRecentlyActiveFields(ctx context.Context, fields TorrentFields) (torrents []Torrent, removed []int64, err error) {
+	return c.TorrentGetRecentlyActive(ctx, fields.Strings())
+}
+
+// TorrentGetIteratorFields returns an iterator over the given typed fields (mandatory) for each ids (optionnal).
+func (c *Client) TorrentGetIteratorFields(ctx context.Context, fields TorrentFields, ids []int64) (it *TorrentIterator, err error) {
+	return c.TorrentGetIterator(ctx, fields.Strings(), ids)
+}
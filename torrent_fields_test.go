@@ -0,0 +1,73 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestTorrentFieldsWith(t *testing.T) {
+	base := transmissionrpc.TorrentFields{transmissionrpc.TorrentFieldID, transmissionrpc.TorrentFieldName}
+	merged := base.With(transmissionrpc.TorrentFieldName, transmissionrpc.TorrentFieldStatus)
+	expected := []string{"id", "name", "status"}
+	if !reflect.DeepEqual(merged.Strings(), expected) {
+		t.Fatalf("expected %v, got %v", expected, merged.Strings())
+	}
+	if len(base) != 2 {
+		t.Fatalf("base set modified: %v", base)
+	}
+}
+
+func TestTorrentFieldsPresets(t *testing.T) {
+	daemon := newStubDaemon(t)
+	daemon.handleTorrents(map[string]interface{}{"id": 1, "name": "ubuntu.iso", "comment": "LTS"})
+	client := daemon.client(t, nil)
+	for name, preset := range map[string]transmissionrpc.TorrentFields{
+		"list":   transmissionrpc.TorrentFieldsListView,
+		"detail": transmissionrpc.TorrentFieldsDetailView,
+		"peer":   transmissionrpc.TorrentFieldsPeerView,
+	} {
+		torrents, err := client.TorrentGetFields(context.Background(), preset, []int64{1})
+		if err != nil {
+			t.Fatalf("%s view: torrent-get failed: %v", name, err)
+		}
+		if len(torrents) != 1 || *torrents[0].Name != "ubuntu.iso" {
+			t.Fatalf("%s view: unexpected torrents: %+v", name, torrents)
+		}
+		var arguments struct {
+			Fields []string `json:"fields"`
+		}
+		if err = json.Unmarshal([]byte(daemon.lastArguments(t, "torrent-get")), &arguments); err != nil {
+			t.Fatalf("%s view: can't decode torrent-get arguments: %v", name, err)
+		}
+		if !reflect.DeepEqual(arguments.Fields, preset.Strings()) {
+			t.Fatalf("%s view: expected fields %v, got %v", name, preset.Strings(), arguments.Fields)
+		}
+	}
+	torrents, err := client.TorrentGetFields(context.Background(), transmissionrpc.TorrentFieldsDetailView, nil)
+	if err != nil || torrents[0].Comment == nil || *torrents[0].Comment != "LTS" {
+		t.Fatalf("expected the detail view to contain the comment, got %+v (err: %v)", torrents, err)
+	}
+	if _, err = client.TorrentGetFields(context.Background(), transmissionrpc.TorrentFields{"nope"}, nil); err == nil {
+		t.Fatal("expected an invalid field to be rejected")
+	}
+}
+
+func TestTorrentFieldsPresetsOnOldDaemons(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 15})
+	daemon.AddTorrent(map[string]interface{}{"name": "ubuntu.iso"})
+	client := newTestClient(t, daemon, nil)
+	for name, preset := range map[string]transmissionrpc.TorrentFields{
+		"list":   transmissionrpc.TorrentFieldsListView,
+		"detail": transmissionrpc.TorrentFieldsDetailView,
+		"peer":   transmissionrpc.TorrentFieldsPeerView,
+	} {
+		if _, err := client.TorrentGetFields(context.Background(), preset, nil); err != nil {
+			t.Fatalf("%s view: torrent-get failed on RPC v15: %v", name, err)
+		}
+	}
+}
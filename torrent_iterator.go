@@ -0,0 +1,342 @@
+package transmissionrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+/*
+	Torrent Iterator
+	Streaming alternative to the torrent-get accessors: torrents are decoded one at a time from the HTTP body.
+*/
+
+// TorrentGetIterator returns an iterator over the given fields (mandatory) for each ids (optionnal).
+// Unlike TorrentGet, torrents are decoded one by one while reading the answer, keeping memory
+// usage low even when requesting heavy fields (files, peers, trackerStats, etc...) on big daemons.
+// The iterator must always be closed.
+func (c *Client) TorrentGetIterator(ctx context.Context, fields []string, ids []int64) (it *TorrentIterator, err error) {
+	if err = c.validateTorrentFields(fields); err != nil {
+		return
+	}
+	return c.torrentGetIterator(ctx, fields, func(fields []string, format string) torrentGetFormatter {
+		return &torrentGetParams{
+			Fields: fields,
+			IDs:    ids,
+			Format: format,
+		}
+	})
+}
+
+// TorrentGetIteratorHashes returns an iterator over the given fields (mandatory) for each hashes (optionnal).
+// The iterator must always be closed.
+func (c *Client) TorrentGetIteratorHashes(ctx context.Context, fields []string, hashes []string) (it *TorrentIterator, err error) {
+	if err = c.validateTorrentFields(fields); err != nil {
+		return
+	}
+	return c.torrentGetIterator(ctx, fields, func(fields []string, format string) torrentGetFormatter {
+		return &torrentGetHashParams{
+			Fields: fields,
+			Hashes: hashes,
+			Format: format,
+		}
+	})
+}
+
+// torrentGetIterator builds the torrent-get params with the fields and format to actually request.
+func (c *Client) torrentGetIterator(ctx context.Context, fields []string, buildParams func(fields []string, format string) torrentGetFormatter) (it *TorrentIterator, err error) {
+	// the timeout also covers the reading of the answer: it is canceled on close
+	ctx, cancel := withCallTimeout(ctx)
+	defer func() {
+		if err != nil || it.preloaded {
+			cancel()
+		}
+	}()
+	fields, emulated, err := c.prepareTorrentFields(ctx, fields)
+	if err != nil {
+		return
+	}
+	format, err := c.torrentGetFormat(ctx)
+	if err != nil {
+		return
+	}
+	params := buildParams(fields, format)
+	c.discoverProtocol(ctx, "torrent-get")
+	if _, legacy := c.wireProtocol().(legacyProtocol); !legacy {
+		// JSON-RPC answers need their names translated: they can not be streamed
+		var torrents []Torrent
+		if torrents, _, err = c.torrentGetRequest(ctx, params, emulated); err != nil {
+			return
+		}
+		it = &TorrentIterator{
+			preloaded: true,
+			torrents:  torrents,
+		}
+		return
+	}
+	var (
+		resp    *http.Response
+		tag     int
+		release func()
+	)
+	// the call leaves the in flight ones once the answer headers are received: holding it while
+	// the caller walks the torrents would block its own calls made during the iteration
+	release, err = c.acquireCall(ctx, "torrent-get")
+	if err != nil {
+		err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+		return
+	}
+	defer release()
+	// the answer is streamed to the iterator: interceptors get a nil result
+	if err = c.invoke(ctx, "torrent-get", params, nil, func(ctx context.Context, method string, arguments, _ interface{}) error {
+		return c.withRetry(ctx, method, func() (err error) {
+			resp, tag, _, err = c.send(ctx, legacyProtocol{}, method, arguments, true)
+			return
+		})
+	}); err != nil {
+		err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+		return
+	}
+	it = &TorrentIterator{
+		cancel:     cancel,
+		body:       resp.Body,
+		decoder:    json.NewDecoder(resp.Body),
+		requestTag: tag,
+		table:      params.format() == torrentGetFormatTable,
+		emulated:   emulated,
+	}
+	return
+}
+
+// TorrentIterator walks the torrents of a torrent-get answer without materializing the whole list.
+// The answer result and tag checks normally done for every request are performed once all the
+// torrents have been read: always check Err() once Next() returns false.
+type TorrentIterator struct {
+	cancel     context.CancelFunc
+	body       io.ReadCloser
+	decoder    *json.Decoder
+	requestTag int
+	table      bool
+	emulated   []string
+	// walk state
+	started      bool
+	inArguments  bool
+	inTorrents   bool
+	torrentsSeen bool
+	done         bool
+	checked      bool
+	closed       bool
+	header       [][]byte
+	buffer       bytes.Buffer
+	// already decoded answer (JSON-RPC protocol)
+	preloaded bool
+	torrents  []Torrent
+	// answer
+	result    string
+	answerTag *int
+	current   Torrent
+	err       error
+}
+
+// Next decodes the next torrent, making it available through Torrent().
+// It returns false when there is no more torrents or if an error occurred.
+func (it *TorrentIterator) Next() bool {
+	if it.preloaded {
+		if it.closed || len(it.torrents) == 0 {
+			return false
+		}
+		it.current, it.torrents = it.torrents[0], it.torrents[1:]
+		return true
+	}
+	var err error
+	for err == nil && !it.done && !it.closed && it.err == nil {
+		if it.inTorrents {
+			if it.decoder.More() {
+				if err = it.decodeTorrent(); err != nil {
+					err = fmt.Errorf("can't decode torrent: %w", err)
+					break
+				}
+				return true
+			}
+			if err = it.expectDelim(']'); err != nil {
+				break
+			}
+			it.inTorrents = false
+			it.torrentsSeen = true
+		}
+		err = it.scan()
+	}
+	if err == nil && it.done && !it.checked {
+		it.checked = true
+		err = checkAnswer("torrent-get", it.result, it.answerTag, it.requestTag)
+	}
+	if err != nil {
+		it.err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+	}
+	return false
+}
+
+// Torrent returns the torrent decoded by the last call to Next().
+func (it *TorrentIterator) Torrent() Torrent {
+	return it.current
+}
+
+// Err returns the error encountered during the iteration, if any.
+func (it *TorrentIterator) Err() error {
+	return it.err
+}
+
+// Close releases the underlying HTTP answer. It can be called before the end of the iteration.
+func (it *TorrentIterator) Close() error {
+	if it.preloaded {
+		it.closed = true
+		it.torrents = nil
+		return nil
+	}
+	if !it.closed {
+		it.cancel()
+	}
+	it.closed = true
+	return it.body.Close()
+}
+
+// scan walks the answer until the beginning of the torrents array or the end of the answer.
+func (it *TorrentIterator) scan() (err error) {
+	if !it.started {
+		if err = it.expectDelim('{'); err != nil {
+			return
+		}
+		it.started = true
+	}
+	if it.inArguments {
+		if err = it.scanArguments(); err != nil || it.inTorrents {
+			return
+		}
+	}
+	var key string
+	for it.decoder.More() {
+		if key, err = it.readKey(); err != nil {
+			return
+		}
+		switch key {
+		case "arguments":
+			if err = it.expectDelim('{'); err != nil {
+				return
+			}
+			it.inArguments = true
+			if err = it.scanArguments(); err != nil || it.inTorrents {
+				return
+			}
+		case "result":
+			if err = it.decoder.Decode(&it.result); err != nil {
+				return fmt.Errorf("can't decode answer result: %w", err)
+			}
+			// fail early if possible
+			if it.result != "success" {
+				return &RPCError{
+					Method: "torrent-get",
+					Result: it.result,
+					Tag:    it.requestTag,
+				}
+			}
+		case "tag":
+			if err = it.decoder.Decode(&it.answerTag); err != nil {
+				return fmt.Errorf("can't decode answer tag: %w", err)
+			}
+		default:
+			if err = it.skipValue(); err != nil {
+				return
+			}
+		}
+	}
+	if err = it.expectDelim('}'); err != nil {
+		return
+	}
+	it.done = true
+	return
+}
+
+func (it *TorrentIterator) scanArguments() (err error) {
+	var key string
+	for it.decoder.More() {
+		if key, err = it.readKey(); err != nil {
+			return
+		}
+		if key != "torrents" || it.torrentsSeen {
+			if err = it.skipValue(); err != nil {
+				return
+			}
+			continue
+		}
+		if err = it.expectDelim('['); err != nil {
+			return
+		}
+		if it.table && it.decoder.More() {
+			var row []json.RawMessage
+			if err = it.decoder.Decode(&row); err != nil {
+				return fmt.Errorf("can't decode table header: %w", err)
+			}
+			if it.header, err = decodeTorrentTableHeader(row); err != nil {
+				return
+			}
+		}
+		it.inTorrents = true
+		return
+	}
+	if err = it.expectDelim('}'); err != nil {
+		return
+	}
+	it.inArguments = false
+	return
+}
+
+func (it *TorrentIterator) decodeTorrent() (err error) {
+	it.current = Torrent{}
+	if !it.table {
+		err = it.decoder.Decode(&it.current)
+	} else {
+		var row []json.RawMessage
+		if err = it.decoder.Decode(&row); err != nil {
+			return
+		}
+		err = decodeTorrentTableRow(it.header, row, &it.current, &it.buffer)
+	}
+	if err == nil && len(it.emulated) > 0 {
+		emulateTorrentFields(&it.current, it.emulated)
+	}
+	return
+}
+
+func (it *TorrentIterator) readKey() (key string, err error) {
+	token, err := it.decoder.Token()
+	if err != nil {
+		return "", fmt.Errorf("can't read answer: %w", err)
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("can't read answer: expecting an object key, got %v", token)
+	}
+	return
+}
+
+func (it *TorrentIterator) expectDelim(delim json.Delim) (err error) {
+	token, err := it.decoder.Token()
+	if err != nil {
+		return fmt.Errorf("can't read answer: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("can't read answer: expecting '%s', got %v", delim, token)
+	}
+	return
+}
+
+func (it *TorrentIterator) skipValue() (err error) {
+	var skip json.RawMessage
+	if err = it.decoder.Decode(&skip); err != nil {
+		return fmt.Errorf("can't read answer: %w", err)
+	}
+	return
+}
@@ -0,0 +1,179 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+// iterate walks all the torrents of it and closes it.
+func iterate(t *testing.T, it *transmissionrpc.TorrentIterator) (torrents []transmissionrpc.Torrent, err error) {
+	t.Helper()
+	defer it.Close()
+	for it.Next() {
+		torrents = append(torrents, it.Torrent())
+	}
+	return torrents, it.Err()
+}
+
+func TestTorrentIteratorFormats(t *testing.T) {
+	daemon := newStubDaemon(t)
+	var stored []map[string]interface{}
+	for index, name := range []string{"first", "second", "third"} {
+		stored = append(stored, map[string]interface{}{
+			"id":         index + 1,
+			"name":       name,
+			"labels":     []interface{}{name},
+			"hashString": fmt.Sprintf("%040x", index+1),
+		})
+	}
+	daemon.handleTorrents(stored...)
+	for _, table := range []bool{false, true} {
+		client := daemon.client(t, &transmissionrpc.Config{TableFormat: table})
+		fields := []string{"id", "name", "labels", "hashString"}
+		expected, err := client.TorrentGet(context.Background(), fields, nil)
+		if err != nil {
+			t.Fatalf("table %t: torrent-get failed: %v", table, err)
+		}
+		it, err := client.TorrentGetIterator(context.Background(), fields, nil)
+		if err != nil {
+			t.Fatalf("table %t: can't get iterator: %v", table, err)
+		}
+		torrents, err := iterate(t, it)
+		if err != nil {
+			t.Fatalf("table %t: iteration failed: %v", table, err)
+		}
+		if len(torrents) != 3 {
+			t.Fatalf("table %t: expected 3 torrents, got %d", table, len(torrents))
+		}
+		for index := range torrents {
+			if *torrents[index].Name != *expected[index].Name || *torrents[index].HashString != *expected[index].HashString ||
+				len(torrents[index].Labels) != 1 || torrents[index].Labels[0] != *expected[index].Name {
+				t.Fatalf("table %t: torrent #%d differs: %+v vs %+v", table, index, torrents[index], expected[index])
+			}
+		}
+		if arguments := daemon.lastArguments(t, "torrent-get"); strings.Contains(arguments, `"format":"table"`) != table {
+			t.Fatalf("table %t: unexpected torrent-get arguments: %s", table, arguments)
+		}
+		// selection by hashes
+		if it, err = client.TorrentGetIteratorHashes(context.Background(), fields, []string{*expected[1].HashString}); err != nil {
+			t.Fatalf("table %t: can't get iterator: %v", table, err)
+		}
+		if torrents, err = iterate(t, it); err != nil || len(torrents) != 1 || *torrents[0].Name != "second" {
+			t.Fatalf("table %t: unexpected hashes iteration: %+v (err: %v)", table, torrents, err)
+		}
+	}
+}
+
+func TestTorrentIteratorEarlyClose(t *testing.T) {
+	daemon := newStubDaemon(t)
+	daemon.handleTorrents(map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}, map[string]interface{}{"id": 3})
+	client := daemon.client(t, nil)
+	it, err := client.TorrentGetIterator(context.Background(), []string{"id"}, nil)
+	if err != nil {
+		t.Fatalf("can't get iterator: %v", err)
+	}
+	if !it.Next() {
+		t.Fatalf("expected a torrent, got error %v", it.Err())
+	}
+	if err = it.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if it.Next() || it.Err() != nil {
+		t.Fatalf("expected a closed iterator to stop without error, got %v", it.Err())
+	}
+}
+
+func TestTorrentIteratorResultError(t *testing.T) {
+	daemon := newStubDaemon(t)
+	daemon.handle("torrent-get", func(json.RawMessage) (interface{}, error) {
+		return nil, errors.New("something went wrong")
+	})
+	client := daemon.client(t, nil)
+	it, err := client.TorrentGetIterator(context.Background(), []string{"id"}, nil)
+	if err != nil {
+		t.Fatalf("can't get iterator: %v", err)
+	}
+	_, err = iterate(t, it)
+	var rpcErr *transmissionrpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Result != "something went wrong" {
+		t.Fatalf("expected the daemon result as RPCError, got %v", err)
+	}
+}
+
+func TestTorrentIteratorTokenWalk(t *testing.T) {
+	// keys order is not guaranteed: result and tag first, unknown keys and values to skip
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Tag int `json:"tag"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		fmt.Fprintf(w, `{"result":"success","tag":%d,"extra":{"nested":[1,{"a":"b"}]},"arguments":{"other":[[1,2],{"c":null}],"torrents":[{"id":1,"name":"first","unknown":{"x":[]}},{"id":2,"name":"second"}],"removed":[]}}`, request.Tag)
+	}))
+	defer server.Close()
+	endpoint, _ := url.Parse(server.URL + "/transmission/rpc")
+	client, err := transmissionrpc.New(endpoint, nil)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	it, err := client.TorrentGetIterator(context.Background(), []string{"id", "name"}, nil)
+	if err != nil {
+		t.Fatalf("can't get iterator: %v", err)
+	}
+	torrents, err := iterate(t, it)
+	if err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if len(torrents) != 2 || *torrents[0].Name != "first" || *torrents[1].ID != 2 {
+		t.Fatalf("unexpected torrents: %+v", torrents)
+	}
+}
+
+func TestTorrentIteratorTagMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"arguments":{"torrents":[{"id":1}]},"result":"success","tag":-1}`)
+	}))
+	defer server.Close()
+	endpoint, _ := url.Parse(server.URL + "/transmission/rpc")
+	client, err := transmissionrpc.New(endpoint, nil)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	it, err := client.TorrentGetIterator(context.Background(), []string{"id"}, nil)
+	if err != nil {
+		t.Fatalf("can't get iterator: %v", err)
+	}
+	// the answer checks happen once all the torrents have been read
+	if !it.Next() {
+		t.Fatalf("expected a torrent before the answer checks, got error %v", it.Err())
+	}
+	if _, err = iterate(t, it); !errors.Is(err, transmissionrpc.ErrTagMismatch) {
+		t.Fatalf("expected ErrTagMismatch, got %v", err)
+	}
+}
+
+func TestTorrentIteratorJSONRPC(t *testing.T) {
+	daemon := newJSONRPCDaemon(t, map[string]string{
+		"torrent_get": `{"torrents":[{"id":1,"download_dir":"/torrent/dir"},{"id":2,"download_dir":"/other/dir"}]}`,
+	})
+	client := daemon.client(t, transmissionrpc.Config{})
+	it, err := client.TorrentGetIterator(context.Background(), []string{"id", "downloadDir"}, nil)
+	if err != nil {
+		t.Fatalf("can't get iterator: %v", err)
+	}
+	torrents, err := iterate(t, it)
+	if err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if len(torrents) != 2 || *torrents[1].DownloadDir != "/other/dir" {
+		t.Fatalf("unexpected torrents: %+v", torrents)
+	}
+}
@@ -17,12 +17,34 @@ import (
 
 // TorrentSet apply a list of mutator(s) to a list of torrent ids.
 func (c *Client) TorrentSet(ctx context.Context, payload TorrentSetPayload) (err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
 	// Validate
 	if len(payload.IDs) == 0 {
 		return errors.New("there must be at least one ID")
 	}
 	// Send payload
-	if err = c.rpcCall(ctx, "torrent-set", payload, nil); err != nil {
+	if err = c.torrentSet(ctx, payload.cleanPayload()); err != nil {
+		err = fmt.Errorf("'torrent-set' rpc method failed: %w", err)
+	}
+	return
+}
+
+// TorrentSetFor apply a list of mutator(s) to the torrent(s) targeted by the selector.
+// The IDs field of the payload is ignored.
+func (c *Client) TorrentSetFor(ctx context.Context, selector IDSelector, payload TorrentSetPayload) (err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	// Validate
+	ids, err := selector.explicitPayload()
+	if err != nil {
+		return
+	}
+	// Build payload
+	cleanPayload := payload.cleanPayload()
+	cleanPayload["ids"] = ids
+	// Send payload
+	if err = c.torrentSet(ctx, cleanPayload); err != nil {
 		err = fmt.Errorf("'torrent-set' rpc method failed: %w", err)
 	}
 	return
@@ -49,6 +71,7 @@ type TorrentSetPayload struct {
 	SeedIdleMode        *int64         `json:"seedIdleMode"`        // which seeding inactivity to use
 	SeedRatioLimit      *float64       `json:"seedRatioLimit"`      // torrent-level seeding ratio
 	SeedRatioMode       *SeedRatioMode `json:"seedRatioMode"`       // which ratio mode to use
+	SequentialDownload  *bool          `json:"sequential_download"` // RPC v18: download the pieces in order
 	TrackerList         []string       `json:"-"`                   // string of announce URLs, one per line, and a blank line between tiers
 	UploadLimit         *int64         `json:"uploadLimit"`         // maximum upload speed (KBps)
 	UploadLimited       *bool          `json:"uploadLimited"`       // true if "uploadLimit" is honored
@@ -58,6 +81,11 @@ type TorrentSetPayload struct {
 // It differs from 'omitempty' which also skip default values
 // (as 0 or false which can be valid here).
 func (tsp TorrentSetPayload) MarshalJSON() (data []byte, err error) {
+	return json.Marshal(tsp.cleanPayload())
+}
+
+// cleanPayload returns the payload in its wire form, containing only the non nil fields.
+func (tsp TorrentSetPayload) cleanPayload() (cleanPayload map[string]interface{}) {
 	// Build an intermediary payload with base types
 	type baseTorrentSetPayload TorrentSetPayload
 	tmp := struct {
@@ -78,7 +106,7 @@ func (tsp TorrentSetPayload) MarshalJSON() (data []byte, err error) {
 	// Build a payload with only the non nil fields
 	tspv := reflect.ValueOf(tmp)
 	tspt := tspv.Type()
-	cleanPayload := make(map[string]interface{}, tspt.NumField())
+	cleanPayload = make(map[string]interface{}, tspt.NumField())
 	var currentValue, nestedStruct, currentNestedValue reflect.Value
 	var currentStructField, currentNestedStructField reflect.StructField
 	var j int
@@ -105,6 +133,5 @@ func (tsp TorrentSetPayload) MarshalJSON() (data []byte, err error) {
 			}
 		}
 	}
-	// Marshall the clean payload
-	return json.Marshal(cleanPayload)
+	return
 }
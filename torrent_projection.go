@@ -0,0 +1,143 @@
+package transmissionrpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/*
+	Torrent Projection
+	Decodes torrent-get answers into caller defined structs.
+*/
+
+var (
+	torrentFieldsIndexes = make(map[string]int) // json name -> Torrent struct field index
+	projectionsCache     sync.Map               // reflect.Type -> *torrentProjection
+)
+
+func init() {
+	torrentType := reflect.TypeOf(Torrent{})
+	for i := 0; i < torrentType.NumField(); i++ {
+		torrentFieldsIndexes[torrentType.Field(i).Tag.Get("json")] = i
+	}
+}
+
+// TorrentGetInto requests the fields declared (as JSON tags) by the T struct for each ids (optionnal)
+// and returns them as T values. Values benefit from the same conversions as the Torrent type
+// (timestamps as time.Time, durations as time.Duration, sizes as cunits.Bits, etc...) and each
+// field of T can either have the type of its Torrent counterpart or its non pointer version.
+// For example:
+//
+//	type myTorrent struct {
+//		ID     int64                         `json:"id"`
+//		Name   string                        `json:"name"`
+//		Added  time.Time                     `json:"addedDate"`
+//		Status transmissionrpc.TorrentStatus `json:"status"`
+//	}
+//	torrents, err := transmissionrpc.TorrentGetInto[myTorrent](ctx, client, nil)
+func TorrentGetInto[T any](ctx context.Context, c *Client, ids []int64) (torrents []T, err error) {
+	ctx, cancel := withCallTimeout(ctx)
+	defer cancel()
+	projection, err := getTorrentProjection(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return
+	}
+	raw, err := c.torrentGet(ctx, projection.fields, ids)
+	if err != nil {
+		return
+	}
+	torrents = make([]T, len(raw))
+	for index := range raw {
+		projection.apply(reflect.ValueOf(&raw[index]).Elem(), reflect.ValueOf(&torrents[index]).Elem())
+	}
+	return
+}
+
+type torrentProjection struct {
+	fields  []string
+	mapping []torrentProjectionField
+}
+
+type torrentProjectionField struct {
+	source int  // Torrent field index
+	target int  // T field index
+	deref  bool // source is a pointer but target is its value
+}
+
+func (tp *torrentProjection) apply(source, target reflect.Value) {
+	var value reflect.Value
+	for _, field := range tp.mapping {
+		value = source.Field(field.source)
+		if field.deref {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		target.Field(field.target).Set(value)
+	}
+}
+
+func getTorrentProjection(targetType reflect.Type) (projection *torrentProjection, err error) {
+	if cached, found := projectionsCache.Load(targetType); found {
+		return cached.(*torrentProjection), nil
+	}
+	if projection, err = newTorrentProjection(targetType); err != nil {
+		return
+	}
+	projectionsCache.Store(targetType, projection)
+	return
+}
+
+func newTorrentProjection(targetType reflect.Type) (projection *torrentProjection, err error) {
+	if targetType.Kind() != reflect.Struct {
+		err = fmt.Errorf("projection type must be a struct, got %s", targetType)
+		return
+	}
+	torrentType := reflect.TypeOf(Torrent{})
+	projection = new(torrentProjection)
+	var (
+		targetField reflect.StructField
+		sourceField reflect.StructField
+		name        string
+		sourceIndex int
+		found       bool
+	)
+	for i := 0; i < targetType.NumField(); i++ {
+		targetField = targetType.Field(i)
+		if !targetField.IsExported() {
+			continue
+		}
+		name, _, _ = strings.Cut(targetField.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if sourceIndex, found = torrentFieldsIndexes[name]; !found {
+			err = fmt.Errorf("field '%s' of %s: torrent field '%s' is invalid", targetField.Name, targetType, name)
+			return
+		}
+		sourceField = torrentType.Field(sourceIndex)
+		mapping := torrentProjectionField{
+			source: sourceIndex,
+			target: i,
+		}
+		switch {
+		case targetField.Type == sourceField.Type:
+		case sourceField.Type.Kind() == reflect.Pointer && targetField.Type == sourceField.Type.Elem():
+			mapping.deref = true
+		default:
+			err = fmt.Errorf("field '%s' of %s: type %s is not compatible with torrent field '%s' type %s",
+				targetField.Name, targetType, targetField.Type, name, sourceField.Type)
+			return
+		}
+		projection.fields = append(projection.fields, name)
+		projection.mapping = append(projection.mapping, mapping)
+	}
+	if len(projection.fields) == 0 {
+		err = fmt.Errorf("%s does not have any torrent field declared with a JSON tag", targetType)
+	}
+	return
+}
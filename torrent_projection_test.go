@@ -0,0 +1,78 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+type projectedTorrent struct {
+	ID      int64                         `json:"id"`
+	Name    string                        `json:"name"`
+	Added   time.Time                     `json:"addedDate"`
+	Status  transmissionrpc.TorrentStatus `json:"status"`
+	Labels  []string                      `json:"labels"`
+	Comment *string                       `json:"comment"`
+	ignored string
+	Skipped string `json:"-"`
+}
+
+func TestTorrentGetInto(t *testing.T) {
+	daemon := newStubDaemon(t)
+	id := int64(1)
+	daemon.handleTorrents(map[string]interface{}{
+		"id":        id,
+		"name":      "ubuntu.iso",
+		"addedDate": 1700000000,
+		"status":    int(transmissionrpc.TorrentStatusStopped),
+		"labels":    []interface{}{"linux"},
+		"comment":   "LTS",
+	})
+	client := daemon.client(t, nil)
+	torrents, err := transmissionrpc.TorrentGetInto[projectedTorrent](context.Background(), client, []int64{id})
+	if err != nil {
+		t.Fatalf("projection failed: %v", err)
+	}
+	if len(torrents) != 1 {
+		t.Fatalf("expected 1 torrent, got %d", len(torrents))
+	}
+	torrent := torrents[0]
+	if torrent.ID != id || torrent.Name != "ubuntu.iso" || !torrent.Added.Equal(time.Unix(1700000000, 0)) ||
+		torrent.Status != transmissionrpc.TorrentStatusStopped || !reflect.DeepEqual(torrent.Labels, []string{"linux"}) ||
+		torrent.Comment == nil || *torrent.Comment != "LTS" {
+		t.Fatalf("unexpected projection: %+v", torrent)
+	}
+	// only the declared fields are requested
+	if args := daemon.lastArguments(t, "torrent-get"); args != `{"fields":["id","name","addedDate","status","labels","comment"],"ids":[1]}` {
+		t.Fatalf("unexpected torrent-get arguments: %s", args)
+	}
+}
+
+func TestTorrentGetIntoInvalidTypes(t *testing.T) {
+	daemon := newStubDaemon(t)
+	client := daemon.client(t, nil)
+	type unknownField struct {
+		Nope string `json:"nope"`
+	}
+	if _, err := transmissionrpc.TorrentGetInto[unknownField](context.Background(), client, nil); err == nil {
+		t.Fatal("expected an unknown field to be rejected")
+	}
+	type incompatibleType struct {
+		Name int `json:"name"`
+	}
+	if _, err := transmissionrpc.TorrentGetInto[incompatibleType](context.Background(), client, nil); err == nil {
+		t.Fatal("expected an incompatible type to be rejected")
+	}
+	type noField struct {
+		Name string
+	}
+	if _, err := transmissionrpc.TorrentGetInto[noField](context.Background(), client, nil); err == nil {
+		t.Fatal("expected a struct without torrent field to be rejected")
+	}
+	if daemon.countRequests("torrent-get") != 0 {
+		t.Fatal("invalid projections must fail before sending anything")
+	}
+}
@@ -19,8 +19,30 @@ func (c *Client) TorrentRemove(ctx context.Context, payload TorrentRemovePayload
 	return
 }
 
+// TorrentRemoveFor allows to delete the torrent(s) targeted by the selector, only or with their data.
+func (c *Client) TorrentRemoveFor(ctx context.Context, selector IDSelector, deleteLocalData bool) (err error) {
+	// Validate
+	ids, err := selector.explicitPayload()
+	if err != nil {
+		return
+	}
+	// Send payload
+	if err = c.rpcCall(ctx, "torrent-remove", &torrentRemoveForPayload{
+		IDs:             ids,
+		DeleteLocalData: deleteLocalData,
+	}, nil); err != nil {
+		return fmt.Errorf("'torrent-remove' rpc method failed: %w", err)
+	}
+	return
+}
+
 // TorrentRemovePayload holds the torrent id(s) to delete with a data deletion flag.
 type TorrentRemovePayload struct {
 	IDs             []int64 `json:"ids"`
 	DeleteLocalData bool    `json:"delete-local-data"`
 }
+
+type torrentRemoveForPayload struct {
+	IDs             interface{} `json:"ids"`
+	DeleteLocalData bool        `json:"delete-local-data"`
+}
@@ -36,6 +36,23 @@ func (c *Client) TorrentRenamePathHash(ctx context.Context, hash, path, name str
 	return
 }
 
+// TorrentRenamePathFor allows to rename the name or a path of the referenced torrent.
+// 'path' is the path to the file or folder that will be renamed.
+// 'name' the file or folder's new name
+func (c *Client) TorrentRenamePathFor(ctx context.Context, ref TorrentRef, path, name string) (err error) {
+	if err = ref.validate(); err != nil {
+		return
+	}
+	if err = c.rpcCall(ctx, "torrent-rename-path", torrentRenamePathForPayload{
+		IDs:  []interface{}{ref.value()},
+		Path: path,
+		Name: name,
+	}, nil); err != nil {
+		err = fmt.Errorf("'torrent-rename-path' rpc method failed: %w", err)
+	}
+	return
+}
+
 type torrentRenamePathPayload struct {
 	IDs  []int64 `json:"ids"`  // the torrent torrent list, as described in 3.1 (must only be 1 torrent)
 	Path string  `json:"path"` // the path to the file or folder that will be renamed
@@ -47,3 +64,9 @@ type torrentRenamePathHashPayload struct {
 	Path   string   `json:"path"` // the path to the file or folder that will be renamed
 	Name   string   `json:"name"` // the file or folder's new name
 }
+
+type torrentRenamePathForPayload struct {
+	IDs  []interface{} `json:"ids"`  // the torrent torrent list, as described in 3.1 (must only be 1 torrent)
+	Path string        `json:"path"` // the path to the file or folder that will be renamed
+	Name string        `json:"name"` // the file or folder's new name
+}
@@ -0,0 +1,174 @@
+package transmissionrpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+/*
+	Torrent Selectors
+	https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#31-torrent-action-requests
+*/
+
+const (
+	recentlyActiveIDs = "recently-active"
+	hashV1Length      = 40 // SHA1 in hex form
+	hashV2Length      = 64 // SHA256 in hex form
+)
+
+// TorrentRef references a single torrent, either by its numeric id or by its info hash (v1 or v2).
+type TorrentRef struct {
+	id   int64
+	hash string
+}
+
+// TorrentRefID returns a reference to the torrent with the given numeric id.
+func TorrentRefID(id int64) TorrentRef {
+	return TorrentRef{id: id}
+}
+
+// TorrentRefHash returns a reference to the torrent with the given info hash (v1 or v2, hex encoded).
+func TorrentRefHash(hash string) TorrentRef {
+	return TorrentRef{hash: hash}
+}
+
+// IsHash returns true if the torrent is referenced by its info hash.
+func (tr TorrentRef) IsHash() bool {
+	return tr.hash != ""
+}
+
+// ID returns the numeric id of the referenced torrent (0 if referenced by hash).
+func (tr TorrentRef) ID() int64 {
+	return tr.id
+}
+
+// Hash returns the info hash of the referenced torrent (empty if referenced by id).
+func (tr TorrentRef) Hash() string {
+	return tr.hash
+}
+
+func (tr TorrentRef) String() string {
+	if tr.IsHash() {
+		return tr.hash
+	}
+	return strconv.FormatInt(tr.id, 10)
+}
+
+func (tr TorrentRef) validate() (err error) {
+	if !tr.IsHash() {
+		if tr.id <= 0 {
+			err = fmt.Errorf("torrent id %d is invalid", tr.id)
+		}
+		return
+	}
+	if len(tr.hash) != hashV1Length && len(tr.hash) != hashV2Length {
+		return fmt.Errorf("torrent hash '%s' is invalid: expecting %d (v1) or %d (v2) hexadecimal characters, got %d",
+			tr.hash, hashV1Length, hashV2Length, len(tr.hash))
+	}
+	if _, err = hex.DecodeString(tr.hash); err != nil {
+		err = fmt.Errorf("torrent hash '%s' is invalid: %w", tr.hash, err)
+	}
+	return
+}
+
+func (tr TorrentRef) value() interface{} {
+	if tr.IsHash() {
+		return tr.hash
+	}
+	return tr.id
+}
+
+// IDSelector selects the torrents a RPC method applies to: all of them (zero value), a list of
+// torrent references (ids, hashes or a mix of both) or the recently active ones.
+type IDSelector struct {
+	refs           []TorrentRef
+	recentlyActive bool
+}
+
+// SelectAll selects all the torrents. It is the IDSelector zero value.
+func SelectAll() IDSelector {
+	return IDSelector{}
+}
+
+// SelectIDs selects the torrents by their numeric ids.
+func SelectIDs(ids ...int64) (selector IDSelector) {
+	selector.refs = make([]TorrentRef, len(ids))
+	for index, id := range ids {
+		selector.refs[index] = TorrentRefID(id)
+	}
+	return
+}
+
+// SelectHashes selects the torrents by their info hashes (v1 or v2, hex encoded).
+func SelectHashes(hashes ...string) (selector IDSelector) {
+	selector.refs = make([]TorrentRef, len(hashes))
+	for index, hash := range hashes {
+		selector.refs[index] = TorrentRefHash(hash)
+	}
+	return
+}
+
+// SelectRefs selects the referenced torrents, which can mix ids and hashes.
+func SelectRefs(refs ...TorrentRef) IDSelector {
+	return IDSelector{refs: refs}
+}
+
+// SelectRecentlyActive selects the torrents which have been recently active.
+func SelectRecentlyActive() IDSelector {
+	return IDSelector{recentlyActive: true}
+}
+
+// IsAll returns true if the selector targets all torrents.
+func (s IDSelector) IsAll() bool {
+	return !s.recentlyActive && len(s.refs) == 0
+}
+
+// IsRecentlyActive returns true if the selector targets the recently active torrents.
+func (s IDSelector) IsRecentlyActive() bool {
+	return s.recentlyActive
+}
+
+// Refs returns the torrent references of the selector (if any).
+func (s IDSelector) Refs() []TorrentRef {
+	return s.refs
+}
+
+// payload returns the selector in its "ids" RPC form. A nil value means all torrents.
+func (s IDSelector) payload() (ids interface{}, err error) {
+	if s.recentlyActive {
+		if len(s.refs) > 0 {
+			err = errors.New("selector can't target both recently active torrents and a torrent list")
+			return
+		}
+		ids = recentlyActiveIDs
+		return
+	}
+	if len(s.refs) == 0 {
+		return
+	}
+	list := make([]interface{}, len(s.refs))
+	for index, ref := range s.refs {
+		if err = ref.validate(); err != nil {
+			return
+		}
+		list[index] = ref.value()
+	}
+	ids = list
+	return
+}
+
+// explicitPayload is the same as payload but refuses to select all torrents. It is used by
+// the methods for which targeting all torrents is most likely an error (remove, set, etc...).
+func (s IDSelector) explicitPayload() (ids interface{}, err error) {
+	if s.IsAll() {
+		err = errors.New("there must be at least one torrent selected")
+		return
+	}
+	return s.payload()
+}
+
+type torrentSelectorParam struct {
+	IDs interface{} `json:"ids,omitempty"`
+}
@@ -0,0 +1,108 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestTorrentSelectors(t *testing.T) {
+	daemon := newStubDaemon(t)
+	for _, method := range []string{"torrent-start", "torrent-stop", "torrent-verify", "torrent-set", "torrent-remove", "torrent-rename-path", "queue-move-top"} {
+		daemon.handle(method, func(json.RawMessage) (interface{}, error) { return nil, nil })
+	}
+	client := daemon.client(t, nil)
+	ctx := context.Background()
+	hash := strings.Repeat("ab", 20)
+	hashV2 := strings.Repeat("cd", 32)
+	labels := []string{"linux"}
+	for _, testCase := range []struct {
+		call      func() error
+		method    string
+		arguments string
+	}{
+		{
+			call: func() error {
+				return client.TorrentStartFor(ctx, transmissionrpc.SelectRefs(
+					transmissionrpc.TorrentRefID(1),
+					transmissionrpc.TorrentRefHash(hash),
+				))
+			},
+			method:    "torrent-start",
+			arguments: `{"ids":[1,"` + hash + `"]}`,
+		},
+		{
+			call:      func() error { return client.TorrentStopFor(ctx, transmissionrpc.SelectRecentlyActive()) },
+			method:    "torrent-stop",
+			arguments: `{"ids":"recently-active"}`,
+		},
+		{
+			call:      func() error { return client.TorrentVerifyFor(ctx, transmissionrpc.SelectAll()) },
+			method:    "torrent-verify",
+			arguments: `{}`,
+		},
+		{
+			call: func() error {
+				return client.TorrentSetFor(ctx, transmissionrpc.SelectHashes(hashV2), transmissionrpc.TorrentSetPayload{Labels: labels})
+			},
+			method:    "torrent-set",
+			arguments: `{"ids":["` + hashV2 + `"],"labels":["linux"]}`,
+		},
+		{
+			call:      func() error { return client.TorrentRemoveFor(ctx, transmissionrpc.SelectIDs(2, 3), true) },
+			method:    "torrent-remove",
+			arguments: `{"ids":[2,3],"delete-local-data":true}`,
+		},
+		{
+			call: func() error {
+				return client.TorrentRenamePathFor(ctx, transmissionrpc.TorrentRefHash(hash), "old", "new")
+			},
+			method:    "torrent-rename-path",
+			arguments: `{"ids":["` + hash + `"],"path":"old","name":"new"}`,
+		},
+		{
+			call:      func() error { return client.QueueMoveTopFor(ctx, transmissionrpc.SelectIDs(4)) },
+			method:    "queue-move-top",
+			arguments: `{"ids":[4]}`,
+		},
+	} {
+		if err := testCase.call(); err != nil {
+			t.Fatalf("%s failed: %v", testCase.method, err)
+		}
+		if arguments := daemon.lastArguments(t, testCase.method); arguments != testCase.arguments {
+			t.Fatalf("%s: expected arguments %s, got %s", testCase.method, testCase.arguments, arguments)
+		}
+	}
+}
+
+func TestTorrentSelectorsValidation(t *testing.T) {
+	daemon := newStubDaemon(t)
+	client := daemon.client(t, nil)
+	ctx := context.Background()
+	for name, call := range map[string]func() error{
+		"invalid hash": func() error {
+			return client.TorrentStartFor(ctx, transmissionrpc.SelectHashes("not-a-hash"))
+		},
+		"invalid id": func() error {
+			return client.TorrentStopFor(ctx, transmissionrpc.SelectIDs(0))
+		},
+		"non hexadecimal hash": func() error {
+			return client.TorrentRenamePathFor(ctx, transmissionrpc.TorrentRefHash(strings.Repeat("z", 40)), "a", "b")
+		},
+		"remove everything": func() error {
+			return client.TorrentRemoveFor(ctx, transmissionrpc.SelectAll(), false)
+		},
+	} {
+		if err := call(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	daemon.mutex.Lock()
+	defer daemon.mutex.Unlock()
+	if len(daemon.requests) != 0 {
+		t.Fatal("invalid selectors must fail before sending anything")
+	}
+}
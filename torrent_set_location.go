@@ -38,6 +38,24 @@ func (c *Client) TorrentSetLocationHash(ctx context.Context, hash, location stri
 	return
 }
 
+// TorrentSetLocationFor allows to set a new location for the torrent(s) targeted by the selector.
+// 'location' is the new torrent location.
+// 'move' if true, move from previous location. Otherwise, search "location" for file.
+func (c *Client) TorrentSetLocationFor(ctx context.Context, selector IDSelector, location string, move bool) (err error) {
+	ids, err := selector.explicitPayload()
+	if err != nil {
+		return
+	}
+	if err = c.rpcCall(ctx, "torrent-set-location", torrentSetLocationForPayload{
+		IDs:      ids,
+		Location: location,
+		Move:     move,
+	}, nil); err != nil {
+		err = fmt.Errorf("'torrent-set-location' rpc method failed: %w", err)
+	}
+	return
+}
+
 type torrentSetLocationPayload struct {
 	IDs      []int64 `json:"ids"`      // torrent list
 	Location string  `json:"location"` // the new torrent location
@@ -49,3 +67,9 @@ type torrentSetLocationHashPayload struct {
 	Location string   `json:"location"` // the new torrent location
 	Move     bool     `json:"move"`     // if true, move from previous location. Otherwise, search "location" for files
 }
+
+type torrentSetLocationForPayload struct {
+	IDs      interface{} `json:"ids"`      // torrent list
+	Location string      `json:"location"` // the new torrent location
+	Move     bool        `json:"move"`     // if true, move from previous location. Otherwise, search "location" for files
+}
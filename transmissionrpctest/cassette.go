@@ -0,0 +1,296 @@
+package transmissionrpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+/*
+	Record & Replay
+	HTTP round trippers capturing real daemon exchanges into a cassette file and replaying them later.
+*/
+
+// Interaction is a recorded RPC exchange.
+type Interaction struct {
+	Method     string          `json:"method"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	StatusCode int             `json:"status_code"`
+	Answer     json.RawMessage `json:"answer,omitempty"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (cassette Cassette, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("can't read cassette file: %w", err)
+		return
+	}
+	if err = json.Unmarshal(data, &cassette); err != nil {
+		err = fmt.Errorf("can't decode cassette file: %w", err)
+	}
+	return
+}
+
+// Save writes the cassette into a file.
+func (c Cassette) Save(path string) (err error) {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return fmt.Errorf("can't encode cassette: %w", err)
+	}
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		err = fmt.Errorf("can't write cassette file: %w", err)
+	}
+	return
+}
+
+// cassetteRequest is a request in either the legacy or the JSON-RPC 2.0 format.
+type cassetteRequest struct {
+	JSONRPC   string          `json:"jsonrpc"`
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments"`
+	Params    json.RawMessage `json:"params"`
+	Tag       *int            `json:"tag"`
+	ID        *int            `json:"id"`
+}
+
+// normalize moves the JSON-RPC 2.0 params and id into the legacy arguments and tag.
+func (cr *cassetteRequest) normalize() {
+	if cr.JSONRPC == "" {
+		return
+	}
+	cr.Arguments = cr.Params
+	cr.Tag = cr.ID
+}
+
+/*
+	Recorder
+*/
+
+// Recorder is a http.RoundTripper recording the RPC exchanges going through it.
+// CSRF handshakes (409 answers) are not recorded as replaying does not need them.
+// Use it through Config.CustomClient, for example with Recorder.HTTPClient().
+type Recorder struct {
+	next         http.RoundTripper
+	mutex        sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder returns a Recorder forwarding the requests to next (a clean pooled transport if nil).
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = cleanhttp.DefaultPooledTransport()
+	}
+	return &Recorder{next: next}
+}
+
+// HTTPClient returns a HTTP client using the recorder as transport.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	// Read request payload while keeping it for the real transport
+	var requestBody []byte
+	if req.Body != nil {
+		if requestBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("recorder can't read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	var request cassetteRequest
+	if err = json.Unmarshal(requestBody, &request); err != nil {
+		return nil, fmt.Errorf("recorder can't decode request payload: %w", err)
+	}
+	request.normalize()
+	// Forward
+	if resp, err = r.next.RoundTrip(req); err != nil {
+		return
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return
+	}
+	// Read answer while keeping it for the caller
+	answerBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recorder can't read answer body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(answerBody))
+	// Record
+	interaction := Interaction{
+		Method:     request.Method,
+		Arguments:  request.Arguments,
+		StatusCode: resp.StatusCode,
+	}
+	if resp.StatusCode == http.StatusOK && json.Valid(answerBody) {
+		interaction.Answer = json.RawMessage(answerBody)
+	}
+	r.mutex.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.mutex.Unlock()
+	return
+}
+
+// Cassette returns the interactions recorded so far.
+func (r *Recorder) Cassette() (cassette Cassette) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	cassette.Interactions = make([]Interaction, len(r.interactions))
+	copy(cassette.Interactions, r.interactions)
+	return
+}
+
+// Save writes the interactions recorded so far into a cassette file.
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+/*
+	Replayer
+*/
+
+// Replayer is a http.RoundTripper answering requests with the interactions of a cassette, without
+// any daemon. Requests are matched by method and arguments (the random tag is ignored and rewritten
+// in the answer so the client tag check still passes). Matching interactions are consumed in order,
+// the last one is reused once all have been consumed. Both the legacy and JSON-RPC 2.0 formats
+// are supported (the method names differ: a cassette is tied to the protocol it was recorded with).
+type Replayer struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+	arguments    []interface{} // decoded arguments of each interaction, for matching
+	used         []bool
+}
+
+// ErrNoInteraction is returned (wrapped) by the Replayer when no recorded interaction matches a request.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// NewReplayer returns a Replayer for the given cassette.
+func NewReplayer(cassette Cassette) (r *Replayer, err error) {
+	r = &Replayer{
+		interactions: cassette.Interactions,
+		arguments:    make([]interface{}, len(cassette.Interactions)),
+		used:         make([]bool, len(cassette.Interactions)),
+	}
+	for index, interaction := range cassette.Interactions {
+		if r.arguments[index], err = decodeMatchable(interaction.Arguments); err != nil {
+			return nil, fmt.Errorf("can't decode arguments of interaction #%d: %w", index, err)
+		}
+	}
+	return
+}
+
+// NewReplayerFromFile returns a Replayer for the given cassette file.
+func NewReplayerFromFile(path string) (r *Replayer, err error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return
+	}
+	return NewReplayer(cassette)
+}
+
+// HTTPClient returns a HTTP client using the replayer as transport.
+func (r *Replayer) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Replayer) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	// Decode request
+	var request cassetteRequest
+	if req.Body != nil {
+		err = json.NewDecoder(req.Body).Decode(&request)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("replayer can't decode request payload: %w", err)
+		}
+		request.normalize()
+	}
+	arguments, err := decodeMatchable(request.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("replayer can't decode request arguments: %w", err)
+	}
+	// Find the interaction
+	interaction, found := r.match(request.Method, arguments)
+	if !found {
+		return nil, fmt.Errorf("%w: method '%s' with arguments %s", ErrNoInteraction, request.Method, request.Arguments)
+	}
+	// Build the answer
+	body := []byte{}
+	if interaction.Answer != nil {
+		var answer map[string]json.RawMessage
+		if err = json.Unmarshal(interaction.Answer, &answer); err != nil {
+			return nil, fmt.Errorf("replayer can't decode recorded answer: %w", err)
+		}
+		tagKey := "tag"
+		if request.JSONRPC != "" {
+			tagKey = "id"
+		}
+		if request.Tag != nil {
+			if answer[tagKey], err = json.Marshal(*request.Tag); err != nil {
+				return nil, fmt.Errorf("replayer can't encode tag: %w", err)
+			}
+		} else {
+			delete(answer, tagKey)
+		}
+		if body, err = json.Marshal(answer); err != nil {
+			return nil, fmt.Errorf("replayer can't encode answer: %w", err)
+		}
+	}
+	resp = &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	return
+}
+
+func (r *Replayer) match(method string, arguments interface{}) (interaction Interaction, found bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	last := -1
+	for index, candidate := range r.interactions {
+		if candidate.Method != method || !reflect.DeepEqual(r.arguments[index], arguments) {
+			continue
+		}
+		if !r.used[index] {
+			r.used[index] = true
+			return candidate, true
+		}
+		last = index
+	}
+	if last != -1 {
+		return r.interactions[last], true
+	}
+	return
+}
+
+// decodeMatchable decodes raw JSON arguments into generic values comparable with reflect.DeepEqual.
+func decodeMatchable(raw json.RawMessage) (value interface{}, err error) {
+	if len(raw) == 0 {
+		return
+	}
+	err = json.Unmarshal(raw, &value)
+	return
+}
@@ -0,0 +1,97 @@
+package transmissionrpctest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	daemon := transmissionrpctest.NewServer()
+	daemon.AddTorrent(map[string]interface{}{"name": "ubuntu.iso"})
+	daemon.AddTorrent(map[string]interface{}{"name": "debian.iso"})
+	endpoint := daemon.URL()
+	ctx := context.Background()
+	fields := []string{"id", "name"}
+	// Record
+	recorder := transmissionrpctest.NewRecorder(nil)
+	client, err := transmissionrpc.New(endpoint, &transmissionrpc.Config{CustomClient: recorder.HTTPClient()})
+	if err != nil {
+		t.Fatalf("can't create recording client: %v", err)
+	}
+	recorded, err := client.TorrentGet(ctx, fields, nil)
+	if err != nil {
+		t.Fatalf("recorded call failed: %v", err)
+	}
+	if err = client.TorrentStopIDs(ctx, []int64{1}); err != nil {
+		t.Fatalf("recorded call failed: %v", err)
+	}
+	daemon.Close()
+	cassette := recorder.Cassette()
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions (409 handshake excluded), got %d", len(cassette.Interactions))
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err = recorder.Save(path); err != nil {
+		t.Fatalf("can't save cassette: %v", err)
+	}
+	// Replay: new random tags must be rewritten within the answers for the client checks to pass
+	replayer, err := transmissionrpctest.NewReplayerFromFile(path)
+	if err != nil {
+		t.Fatalf("can't load cassette: %v", err)
+	}
+	client, err = transmissionrpc.New(endpoint, &transmissionrpc.Config{CustomClient: replayer.HTTPClient()})
+	if err != nil {
+		t.Fatalf("can't create replaying client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		replayed, err := client.TorrentGet(ctx, fields, nil)
+		if err != nil {
+			t.Fatalf("replayed call #%d failed: %v", i, err)
+		}
+		if len(replayed) != len(recorded) || *replayed[0].Name != *recorded[0].Name || *replayed[1].Name != *recorded[1].Name {
+			t.Fatalf("replayed call #%d: expected %d torrents, got %+v", i, len(recorded), replayed)
+		}
+	}
+	if err = client.TorrentStopIDs(ctx, []int64{1}); err != nil {
+		t.Fatalf("replayed call failed: %v", err)
+	}
+	// Unknown request
+	if err = client.TorrentStopIDs(ctx, []int64{2}); !errors.Is(err, transmissionrpctest.ErrNoInteraction) {
+		t.Fatalf("expected ErrNoInteraction, got %v", err)
+	}
+}
+
+func TestCassetteReplayJSONRPC(t *testing.T) {
+	replayer, err := transmissionrpctest.NewReplayer(transmissionrpctest.Cassette{
+		Interactions: []transmissionrpctest.Interaction{{
+			Method:     "session_stats",
+			StatusCode: 200,
+			Answer:     json.RawMessage(`{"jsonrpc":"2.0","result":{"torrent_count":3,"active_torrent_count":1},"id":1}`),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("can't create replayer: %v", err)
+	}
+	endpoint, _ := url.Parse("http://127.0.0.1:9091/transmission/rpc")
+	client, err := transmissionrpc.New(endpoint, &transmissionrpc.Config{
+		CustomClient: replayer.HTTPClient(),
+		Protocol:     transmissionrpc.ProtocolJSONRPC,
+	})
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	stats, err := client.SessionStats(context.Background())
+	if err != nil {
+		t.Fatalf("replayed call failed: %v", err)
+	}
+	if stats.TorrentCount != 3 || stats.ActiveTorrentCount != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
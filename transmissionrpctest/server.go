@@ -0,0 +1,335 @@
+// Package transmissionrpctest provides an in-memory fake Transmission daemon for tests.
+//
+// The fake daemon speaks the same RPC protocol as a real one (CSRF session id handshake,
+// tag echoing, torrent/session/group methods, etc...) and is backed by a mutable in-memory
+// state which can be inspected and modified by the tests. Failures and latency can also be
+// injected to exercise error paths.
+package transmissionrpctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+const (
+	// RPCPath is the path on which the fake daemon answers RPC requests.
+	RPCPath    = "/transmission/rpc"
+	csrfHeader = "X-Transmission-Session-Id"
+	// torrents changed or removed within this time frame are returned by the "recently-active" mode
+	recentlyActiveWindow = 60 * time.Second
+)
+
+// Server is an in-memory fake Transmission daemon served over HTTP.
+// It must be created with NewServer() and closed with Close().
+type Server struct {
+	http *httptest.Server
+	// State
+	mutex     sync.Mutex
+	sessionID string
+	torrents  map[int64]*torrent
+	nextID    int64
+	removed   []removedTorrent
+	session   map[string]interface{}
+	stats     map[string]interface{}
+	groups    map[string]map[string]interface{}
+	freeSpace map[string][2]int64 // path -> free, total
+	portOpen  bool
+	// Hooks
+	latency   time.Duration
+	failures  map[string][]Failure
+	onRequest func(method string, arguments json.RawMessage)
+	requests  []Request
+}
+
+// Failure describes an error the fake daemon will answer instead of processing a request.
+type Failure struct {
+	// StatusCode, if set, is the HTTP status code answered (with an empty body).
+	StatusCode int
+	// Result, if set (and StatusCode is not), is the non "success" result answered within a valid payload.
+	Result string
+}
+
+// Request is a request received (and authorized) by the fake daemon.
+type Request struct {
+	Method    string
+	Arguments json.RawMessage
+}
+
+type torrent struct {
+	fields    map[string]interface{}
+	changedAt time.Time
+}
+
+type removedTorrent struct {
+	id        int64
+	removedAt time.Time
+}
+
+// NewServer starts and returns a new fake daemon with an empty torrent list and default session values.
+func NewServer() (s *Server) {
+	s = &Server{
+		sessionID: newSessionID(),
+		torrents:  make(map[int64]*torrent),
+		nextID:    1,
+		session:   defaultSession(),
+		stats:     defaultStats(),
+		groups:    make(map[string]map[string]interface{}),
+		freeSpace: make(map[string][2]int64),
+		portOpen:  true,
+		failures:  make(map[string][]Failure),
+	}
+	s.http = httptest.NewServer(http.HandlerFunc(s.handle))
+	return
+}
+
+// Close shuts down the fake daemon.
+func (s *Server) Close() {
+	s.http.Close()
+}
+
+// URL returns the RPC endpoint of the fake daemon.
+func (s *Server) URL() *url.URL {
+	endpoint, err := url.Parse(s.http.URL + RPCPath)
+	if err != nil {
+		panic(fmt.Sprintf("can't parse fake daemon URL: %v", err))
+	}
+	return endpoint
+}
+
+// Client returns a transmissionrpc client configured to talk to the fake daemon.
+func (s *Server) Client(config *transmissionrpc.Config) (*transmissionrpc.Client, error) {
+	return transmissionrpc.New(s.URL(), config)
+}
+
+/*
+	Hooks
+*/
+
+// SetLatency makes the fake daemon wait for the given duration before answering each request.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.latency = latency
+}
+
+// FailNext makes the next request for the given method (any method if empty) fail as described.
+// Several failures can be queued for the same method, they will be used in order.
+func (s *Server) FailNext(method string, failure Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures[method] = append(s.failures[method], failure)
+}
+
+// OnRequest registers a function called for each authorized request, before it is processed.
+func (s *Server) OnRequest(hook func(method string, arguments json.RawMessage)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onRequest = hook
+}
+
+// Requests returns the authorized requests received so far.
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// RotateSessionID changes the CSRF session id, as a daemon restart would.
+func (s *Server) RotateSessionID() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessionID = newSessionID()
+}
+
+// SessionID returns the current CSRF session id.
+func (s *Server) SessionID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sessionID
+}
+
+/*
+	HTTP handling
+*/
+
+type requestPayload struct {
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments"`
+	Tag       *int            `json:"tag"`
+}
+
+type answerPayload struct {
+	Arguments interface{} `json:"arguments"`
+	Result    string      `json:"result"`
+	Tag       *int        `json:"tag,omitempty"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != RPCPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	// Latency
+	s.mutex.Lock()
+	latency := s.latency
+	s.mutex.Unlock()
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	// CSRF protection
+	s.mutex.Lock()
+	sessionID := s.sessionID
+	s.mutex.Unlock()
+	if r.Header.Get(csrfHeader) != sessionID {
+		w.Header().Set(csrfHeader, sessionID)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	// Decode request
+	var request requestPayload
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// Hooks
+	s.mutex.Lock()
+	s.requests = append(s.requests, Request{
+		Method:    request.Method,
+		Arguments: request.Arguments,
+	})
+	hook := s.onRequest
+	failure, fail := s.popFailure(request.Method)
+	s.mutex.Unlock()
+	if hook != nil {
+		hook(request.Method, request.Arguments)
+	}
+	if fail && failure.StatusCode != 0 {
+		w.WriteHeader(failure.StatusCode)
+		return
+	}
+	// Process
+	answer := answerPayload{Tag: request.Tag}
+	if fail {
+		answer.Result = failure.Result
+	} else {
+		var err error
+		if answer.Arguments, err = s.dispatch(request.Method, request.Arguments); err != nil {
+			answer.Result = err.Error()
+		} else {
+			answer.Result = "success"
+		}
+	}
+	if answer.Arguments == nil {
+		answer.Arguments = struct{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(answer)
+}
+
+// popFailure must be called with the mutex held.
+func (s *Server) popFailure(method string) (failure Failure, found bool) {
+	for _, key := range []string{method, ""} {
+		if queue := s.failures[key]; len(queue) > 0 {
+			failure = queue[0]
+			s.failures[key] = queue[1:]
+			return failure, true
+		}
+	}
+	return
+}
+
+type rpcError string
+
+func (re rpcError) Error() string {
+	return string(re)
+}
+
+func (s *Server) dispatch(method string, arguments json.RawMessage) (answer interface{}, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch method {
+	// Torrents
+	case "torrent-start", "torrent-start-now", "torrent-stop", "torrent-verify", "torrent-reannounce":
+		return s.torrentAction(method, arguments)
+	case "torrent-set":
+		return s.torrentSet(arguments)
+	case "torrent-get":
+		return s.torrentGet(arguments)
+	case "torrent-add":
+		return s.torrentAdd(arguments)
+	case "torrent-remove":
+		return s.torrentRemove(arguments)
+	case "torrent-set-location":
+		return s.torrentSetLocation(arguments)
+	case "torrent-rename-path":
+		return s.torrentRenamePath(arguments)
+	// Session
+	case "session-get":
+		return s.sessionGet(arguments)
+	case "session-set":
+		return s.sessionSet(arguments)
+	case "session-stats":
+		return s.sessionStats()
+	case "session-close":
+		return nil, nil
+	case "blocklist-update":
+		return map[string]interface{}{"blocklist-size": s.session["blocklist-size"]}, nil
+	case "port-test":
+		return map[string]interface{}{"port-is-open": s.portOpen}, nil
+	case "queue-move-top", "queue-move-up", "queue-move-down", "queue-move-bottom":
+		return s.queueMove(method, arguments)
+	case "free-space":
+		return s.freeSpaceGet(arguments)
+	case "group-get":
+		return s.groupGet(arguments)
+	case "group-set":
+		return s.groupSet(arguments)
+	default:
+		return nil, rpcError("method name not recognized")
+	}
+}
+
+/*
+	Helpers
+*/
+
+func newSessionID() string {
+	return randomHex(24)
+}
+
+func randomHex(size int) string {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		panic(fmt.Sprintf("can't generate random bytes: %v", err))
+	}
+	return hex.EncodeToString(buffer)
+}
+
+func decodeArguments(arguments json.RawMessage, target interface{}) (err error) {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		return
+	}
+	if err = json.Unmarshal(arguments, target); err != nil {
+		err = rpcError(fmt.Sprintf("invalid arguments: %v", err))
+	}
+	return
+}
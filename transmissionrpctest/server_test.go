@@ -0,0 +1,140 @@
+package transmissionrpctest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+// post sends a raw legacy request to the daemon.
+func post(t *testing.T, daemon *transmissionrpctest.Server, sessionID, payload string) (resp *http.Response, answer map[string]json.RawMessage) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, daemon.URL().String(), bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("can't build request: %v", err)
+	}
+	req.Header.Set("X-Transmission-Session-Id", sessionID)
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if err = json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+			t.Fatalf("can't decode answer: %v", err)
+		}
+	}
+	return
+}
+
+func TestServerCSRFHandshake(t *testing.T) {
+	daemon := transmissionrpctest.NewServer()
+	defer daemon.Close()
+	// without session id
+	resp, _ := post(t, daemon, "", `{"method":"session-stats","tag":1}`)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+	sessionID := resp.Header.Get("X-Transmission-Session-Id")
+	if sessionID == "" || sessionID != daemon.SessionID() {
+		t.Fatalf("expected the session id %q within the 409 answer, got %q", daemon.SessionID(), sessionID)
+	}
+	if len(daemon.Requests()) != 0 {
+		t.Fatal("unauthorized request recorded")
+	}
+	// with it
+	resp, answer := post(t, daemon, sessionID, `{"method":"session-stats","tag":1}`)
+	if resp.StatusCode != http.StatusOK || string(answer["result"]) != `"success"` {
+		t.Fatalf("expected a successful answer, got %d %s", resp.StatusCode, answer["result"])
+	}
+	// rotated
+	daemon.RotateSessionID()
+	if resp, _ = post(t, daemon, sessionID, `{"method":"session-stats","tag":1}`); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 after rotation, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerTagEcho(t *testing.T) {
+	daemon := transmissionrpctest.NewServer()
+	defer daemon.Close()
+	for _, tc := range []struct {
+		payload string
+		tag     string
+	}{
+		{payload: `{"method":"session-stats","tag":4242}`, tag: "4242"},
+		{payload: `{"method":"session-stats"}`, tag: ""},
+	} {
+		_, answer := post(t, daemon, daemon.SessionID(), tc.payload)
+		if tag := string(answer["tag"]); tag != tc.tag {
+			t.Fatalf("payload %s: expected tag %q, got %q", tc.payload, tc.tag, tag)
+		}
+	}
+}
+
+func TestServerFailNext(t *testing.T) {
+	daemon := transmissionrpctest.NewServer()
+	defer daemon.Close()
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{StatusCode: http.StatusInternalServerError})
+	daemon.FailNext("session-stats", transmissionrpctest.Failure{Result: "something went wrong"})
+	daemon.FailNext("", transmissionrpctest.Failure{Result: "any method"})
+	var hooked []string
+	daemon.OnRequest(func(method string, _ json.RawMessage) {
+		hooked = append(hooked, method)
+	})
+	client, err := daemon.Client(nil)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	ctx := context.Background()
+	// queued failures of the method, in order
+	_, err = client.SessionStats(ctx)
+	var statusCode transmissionrpc.HTTPStatusCode
+	if !errors.As(err, &statusCode) || statusCode != http.StatusInternalServerError {
+		t.Fatalf("expected a 500 HTTPStatusCode, got %v", err)
+	}
+	_, err = client.SessionStats(ctx)
+	var rpcErr *transmissionrpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Result != "something went wrong" {
+		t.Fatalf("expected the queued result, got %v", err)
+	}
+	// then the one of any method
+	if _, _, err = client.FreeSpace(ctx, "/downloads"); !errors.As(err, &rpcErr) || rpcErr.Result != "any method" {
+		t.Fatalf("expected the queued result of any method, got %v", err)
+	}
+	// queues are empty
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("expected a successful call, got %v", err)
+	}
+	if len(hooked) != 4 || len(daemon.Requests()) != 4 {
+		t.Fatalf("expected 4 hooked and recorded requests, got %v and %d", hooked, len(daemon.Requests()))
+	}
+}
+
+func TestServerState(t *testing.T) {
+	daemon := transmissionrpctest.NewServer()
+	defer daemon.Close()
+	id := daemon.AddTorrent(map[string]interface{}{"name": "ubuntu.iso"})
+	client, err := daemon.Client(nil)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	ctx := context.Background()
+	if err = client.TorrentStartIDs(ctx, []int64{id}); err != nil {
+		t.Fatalf("can't start torrent: %v", err)
+	}
+	fields, found := daemon.Torrent(id)
+	if !found || fields["status"] != int(transmissionrpc.TorrentStatusDownload) {
+		t.Fatalf("expected the torrent to be downloading, got %v", fields["status"])
+	}
+	if err = client.TorrentRemove(ctx, transmissionrpc.TorrentRemovePayload{IDs: []int64{id}}); err != nil {
+		t.Fatalf("can't remove torrent: %v", err)
+	}
+	if ids := daemon.TorrentIDs(); len(ids) != 0 {
+		t.Fatalf("expected no torrent left, got %v", ids)
+	}
+}
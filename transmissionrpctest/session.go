@@ -0,0 +1,285 @@
+package transmissionrpctest
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+const (
+	defaultFreeSpace  = 100 << 30
+	defaultTotalSpace = 500 << 30
+)
+
+/*
+	Session state
+*/
+
+// Session returns a copy of the session values (using their session-get names).
+func (s *Server) Session() (fields map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fields = make(map[string]interface{}, len(s.session))
+	for key, value := range s.session {
+		fields[key] = value
+	}
+	return
+}
+
+// UpdateSession modifies the given session values (using their session-get names), including
+// the read only ones (rpc-version, version, etc...) which can not be changed through session-set.
+func (s *Server) UpdateSession(fields map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, value := range fields {
+		s.session[key] = value
+	}
+}
+
+// UpdateStats modifies the given session-stats values (top level ones only).
+func (s *Server) UpdateStats(fields map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, value := range fields {
+		s.stats[key] = value
+	}
+}
+
+// SetFreeSpace sets the free and total space (in bytes) answered by free-space for a given path.
+// Unknown paths get 100GiB free on 500GiB.
+func (s *Server) SetFreeSpace(path string, free, total int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.freeSpace[path] = [2]int64{free, total}
+}
+
+// SetPortOpen sets the port-test result.
+func (s *Server) SetPortOpen(open bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.portOpen = open
+}
+
+func defaultSession() map[string]interface{} {
+	return map[string]interface{}{
+		"alt-speed-down":                       50,
+		"alt-speed-enabled":                    false,
+		"alt-speed-time-begin":                 540,
+		"alt-speed-time-day":                   127,
+		"alt-speed-time-enabled":               false,
+		"alt-speed-time-end":                   1020,
+		"alt-speed-up":                         50,
+		"blocklist-enabled":                    false,
+		"blocklist-size":                       0,
+		"blocklist-url":                        "http://www.example.com/blocklist",
+		"cache-size-mb":                        4,
+		"config-dir":                           "/var/lib/transmission",
+		"default-trackers":                     "",
+		"dht-enabled":                          true,
+		"download-dir":                         "/downloads",
+		"download-queue-enabled":               true,
+		"download-queue-size":                  5,
+		"encryption":                           "preferred",
+		"idle-seeding-limit-enabled":           false,
+		"idle-seeding-limit":                   30,
+		"incomplete-dir-enabled":               false,
+		"incomplete-dir":                       "/downloads/incomplete",
+		"lpd-enabled":                          false,
+		"peer-limit-global":                    200,
+		"peer-limit-per-torrent":               50,
+		"peer-port-random-on-start":            false,
+		"peer-port":                            51413,
+		"pex-enabled":                          true,
+		"port-forwarding-enabled":              true,
+		"queue-stalled-enabled":                true,
+		"queue-stalled-minutes":                30,
+		"rename-partial-files":                 true,
+		"rpc-version-minimum":                  14,
+		"rpc-version-semver":                   "5.3.0",
+		"rpc-version":                          17,
+		"script-torrent-added-enabled":         false,
+		"script-torrent-added-filename":        "",
+		"script-torrent-done-enabled":          false,
+		"script-torrent-done-filename":         "",
+		"script-torrent-done-seeding-enabled":  false,
+		"script-torrent-done-seeding-filename": "",
+		"seed-queue-enabled":                   false,
+		"seed-queue-size":                      10,
+		"seedRatioLimit":                       2.0,
+		"seedRatioLimited":                     false,
+		"session-id":                           randomHex(16),
+		"speed-limit-down-enabled":             false,
+		"speed-limit-down":                     100,
+		"speed-limit-up-enabled":               false,
+		"speed-limit-up":                       100,
+		"start-added-torrents":                 true,
+		"trash-original-torrent-files":         false,
+		"units": map[string]interface{}{
+			"speed-units":  []string{"kB/s", "MB/s", "GB/s", "TB/s"},
+			"speed-bytes":  1000,
+			"size-units":   []string{"kB", "MB", "GB", "TB"},
+			"size-bytes":   1000,
+			"memory-units": []string{"KiB", "MiB", "GiB", "TiB"},
+			"memory-bytes": 1024,
+		},
+		"utp-enabled": true,
+		"version":     "4.0.3 (fake)",
+	}
+}
+
+func defaultStats() map[string]interface{} {
+	details := func() map[string]interface{} {
+		return map[string]interface{}{
+			"downloadedBytes": 0,
+			"filesAdded":      0,
+			"secondsActive":   0,
+			"sessionCount":    1,
+			"uploadedBytes":   0,
+		}
+	}
+	return map[string]interface{}{
+		"downloadSpeed":    0,
+		"uploadSpeed":      0,
+		"cumulative-stats": details(),
+		"current-stats":    details(),
+	}
+}
+
+// readOnlySessionFields can not be modified with session-set.
+var readOnlySessionFields = map[string]bool{
+	"blocklist-size":      true,
+	"config-dir":          true,
+	"rpc-version-minimum": true,
+	"rpc-version-semver":  true,
+	"rpc-version":         true,
+	"session-id":          true,
+	"units":               true,
+	"version":             true,
+}
+
+/*
+	Session RPC methods
+*/
+
+func (s *Server) sessionGet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		Fields []string `json:"fields"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	result := make(map[string]interface{}, len(s.session))
+	if len(args.Fields) == 0 {
+		for key, value := range s.session {
+			result[key] = value
+		}
+		return result, nil
+	}
+	for _, field := range args.Fields {
+		if value, found := s.session[field]; found {
+			result[field] = value
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) sessionSet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args map[string]interface{}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	for key, value := range args {
+		if !readOnlySessionFields[key] {
+			s.session[key] = value
+		}
+	}
+	return
+}
+
+func (s *Server) sessionStats() (answer interface{}, err error) {
+	result := make(map[string]interface{}, len(s.stats)+3)
+	for key, value := range s.stats {
+		result[key] = value
+	}
+	var active, paused int
+	for _, t := range s.torrents {
+		if toInt64(t.fields["status"]) == statusStopped {
+			paused++
+		} else {
+			active++
+		}
+	}
+	result["activeTorrentCount"] = active
+	result["pausedTorrentCount"] = paused
+	result["torrentCount"] = len(s.torrents)
+	return result, nil
+}
+
+func (s *Server) freeSpaceGet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	space, found := s.freeSpace[args.Path]
+	if !found {
+		space = [2]int64{defaultFreeSpace, defaultTotalSpace}
+	}
+	return map[string]interface{}{
+		"path":       args.Path,
+		"size-bytes": space[0],
+		"total_size": space[1],
+	}, nil
+}
+
+/*
+	Bandwidth groups RPC methods
+*/
+
+func (s *Server) groupGet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		Group interface{} `json:"group"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	// As transmission 4.0.3 does, the filter is ignored
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	groups := make([]map[string]interface{}, len(names))
+	for index, name := range names {
+		groups[index] = s.groups[name]
+	}
+	return map[string]interface{}{"group": groups}, nil
+}
+
+func (s *Server) groupSet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args map[string]interface{}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	name, _ := args["name"].(string)
+	if name == "" {
+		err = rpcError("No group name given")
+		return
+	}
+	group, found := s.groups[name]
+	if !found {
+		group = map[string]interface{}{
+			"honorsSessionLimits":      true,
+			"name":                     name,
+			"speed-limit-down-enabled": false,
+			"speed-limit-down":         0,
+			"speed-limit-up-enabled":   false,
+			"speed-limit-up":           0,
+		}
+		s.groups[name] = group
+	}
+	for key, value := range args {
+		group[key] = value
+	}
+	return
+}
@@ -0,0 +1,760 @@
+package transmissionrpctest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	Torrents state
+*/
+
+// AddTorrent adds a torrent to the fake daemon state and returns its id. The given fields (using
+// their torrent-get names) are merged over sensible defaults, "id" is always assigned by the daemon.
+func (s *Server) AddTorrent(fields map[string]interface{}) (id int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	name, _ := fields["name"].(string)
+	hash, _ := fields["hashString"].(string)
+	t := s.newTorrent(name, hash)
+	for key, value := range fields {
+		if key != "id" {
+			t.fields[key] = value
+		}
+	}
+	return toInt64(t.fields["id"])
+}
+
+// Torrent returns a copy of the fields of the torrent with the given id.
+func (s *Server) Torrent(id int64) (fields map[string]interface{}, found bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t, found := s.torrents[id]
+	if !found {
+		return
+	}
+	fields = make(map[string]interface{}, len(t.fields))
+	for key, value := range t.fields {
+		fields[key] = value
+	}
+	return
+}
+
+// UpdateTorrent modifies the given fields (using their torrent-get names) of a torrent.
+// It returns false if the torrent does not exist.
+func (s *Server) UpdateTorrent(id int64, fields map[string]interface{}) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	t, found := s.torrents[id]
+	if !found {
+		return false
+	}
+	for key, value := range fields {
+		if key != "id" {
+			t.fields[key] = value
+		}
+	}
+	t.changedAt = time.Now()
+	return true
+}
+
+// RemoveTorrent removes a torrent from the fake daemon state.
+func (s *Server) RemoveTorrent(id int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.removeTorrent(id)
+}
+
+// TorrentIDs returns the ids of all the torrents, sorted.
+func (s *Server) TorrentIDs() []int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sortedIDs()
+}
+
+// newTorrent must be called with the mutex held.
+func (s *Server) newTorrent(name, hash string) (t *torrent) {
+	id := s.nextID
+	s.nextID++
+	if hash == "" {
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s-%d-%s", name, id, randomHex(8))))
+		hash = hex.EncodeToString(sum[:])
+	}
+	if name == "" {
+		name = fmt.Sprintf("torrent-%d", id)
+	}
+	now := time.Now()
+	t = &torrent{
+		fields: map[string]interface{}{
+			"activityDate":        0,
+			"addedDate":           now.Unix(),
+			"bandwidthPriority":   0,
+			"comment":             "",
+			"corruptEver":         0,
+			"creator":             "",
+			"dateCreated":         0,
+			"desiredAvailable":    0,
+			"doneDate":            0,
+			"downloadDir":         s.session["download-dir"],
+			"downloadedEver":      0,
+			"downloadLimit":       100,
+			"downloadLimited":     false,
+			"editDate":            0,
+			"error":               0,
+			"errorString":         "",
+			"eta":                 -1,
+			"etaIdle":             -1,
+			"file-count":          0,
+			"files":               []interface{}{},
+			"fileStats":           []interface{}{},
+			"group":               "",
+			"hashString":          hash,
+			"haveUnchecked":       0,
+			"haveValid":           0,
+			"honorsSessionLimits": true,
+			"id":                  id,
+			"isFinished":          false,
+			"isPrivate":           false,
+			"isStalled":           false,
+			"labels":              []interface{}{},
+			"leftUntilDone":       0,
+			"magnetLink":          fmt.Sprintf("magnet:?xt=urn:btih:%s&dn=%s", hash, url.QueryEscape(name)),
+			"name":                name,
+			"peer-limit":          50,
+			"peers":               []interface{}{},
+			"peersConnected":      0,
+			"peersGettingFromUs":  0,
+			"peersSendingToUs":    0,
+			"percentComplete":     0.0,
+			"percentDone":         0.0,
+			"pieceCount":          0,
+			"pieceSize":           0,
+			"priorities":          []interface{}{},
+			"queuePosition":       len(s.torrents),
+			"rateDownload":        0,
+			"rateUpload":          0,
+			"recheckProgress":     0.0,
+			"secondsDownloading":  0,
+			"secondsSeeding":      0,
+			"seedIdleLimit":       30,
+			"seedIdleMode":        0,
+			"seedRatioLimit":      2.0,
+			"seedRatioMode":       0,
+			"sizeWhenDone":        0,
+			"startDate":           0,
+			"status":              statusStopped,
+			"totalSize":           0,
+			"trackerList":         "",
+			"trackers":            []interface{}{},
+			"trackerStats":        []interface{}{},
+			"uploadedEver":        0,
+			"uploadLimit":         100,
+			"uploadLimited":       false,
+			"uploadRatio":         0.0,
+			"wanted":              []interface{}{},
+			"webseeds":            []interface{}{},
+			"webseedsSendingToUs": 0,
+		},
+		changedAt: now,
+	}
+	s.torrents[id] = t
+	return
+}
+
+// removeTorrent must be called with the mutex held.
+func (s *Server) removeTorrent(id int64) {
+	if _, found := s.torrents[id]; !found {
+		return
+	}
+	delete(s.torrents, id)
+	s.removed = append(s.removed, removedTorrent{id: id, removedAt: time.Now()})
+	s.normalizeQueue()
+}
+
+// sortedIDs must be called with the mutex held.
+func (s *Server) sortedIDs() (ids []int64) {
+	ids = make([]int64, 0, len(s.torrents))
+	for id := range s.torrents {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return
+}
+
+const (
+	statusStopped  = 0
+	statusCheck    = 2
+	statusDownload = 4
+	statusSeed     = 6
+)
+
+/*
+	Torrents selection
+*/
+
+type selection struct {
+	ids            []int64
+	recentlyActive bool
+}
+
+// selectTorrents parses the "ids" argument. Must be called with the mutex held.
+func (s *Server) selectTorrents(raw json.RawMessage) (sel selection, err error) {
+	// All torrents
+	if len(raw) == 0 || string(raw) == "null" {
+		sel.ids = s.sortedIDs()
+		return
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(raw, &decoded); err != nil {
+		err = rpcError(fmt.Sprintf("invalid ids: %v", err))
+		return
+	}
+	var list []interface{}
+	switch v := decoded.(type) {
+	case string:
+		if v == "recently-active" {
+			sel.recentlyActive = true
+			threshold := time.Now().Add(-recentlyActiveWindow)
+			for _, id := range s.sortedIDs() {
+				if s.torrents[id].changedAt.After(threshold) {
+					sel.ids = append(sel.ids, id)
+				}
+			}
+			return
+		}
+		list = []interface{}{v}
+	case float64:
+		list = []interface{}{v}
+	case []interface{}:
+		list = v
+	default:
+		err = rpcError(fmt.Sprintf("invalid ids: %s", raw))
+		return
+	}
+	seen := make(map[int64]struct{}, len(list))
+	for _, item := range list {
+		var id int64
+		switch ref := item.(type) {
+		case float64:
+			id = int64(ref)
+			if _, found := s.torrents[id]; !found {
+				continue
+			}
+		case string:
+			if id = s.findHash(ref); id == 0 {
+				continue
+			}
+		default:
+			err = rpcError(fmt.Sprintf("invalid id: %v", item))
+			return
+		}
+		if _, found := seen[id]; !found {
+			seen[id] = struct{}{}
+			sel.ids = append(sel.ids, id)
+		}
+	}
+	return
+}
+
+// findHash must be called with the mutex held.
+func (s *Server) findHash(hash string) int64 {
+	for id, t := range s.torrents {
+		if strings.EqualFold(t.fields["hashString"].(string), hash) {
+			return id
+		}
+	}
+	return 0
+}
+
+/*
+	Torrents RPC methods
+*/
+
+type idsArguments struct {
+	IDs json.RawMessage `json:"ids"`
+}
+
+func (s *Server) torrentAction(method string, arguments json.RawMessage) (answer interface{}, err error) {
+	var args idsArguments
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	sel, err := s.selectTorrents(args.IDs)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, id := range sel.ids {
+		t := s.torrents[id]
+		switch method {
+		case "torrent-start", "torrent-start-now":
+			if toFloat64(t.fields["percentDone"]) >= 1 {
+				t.fields["status"] = statusSeed
+			} else {
+				t.fields["status"] = statusDownload
+			}
+			t.fields["startDate"] = now.Unix()
+		case "torrent-stop":
+			t.fields["status"] = statusStopped
+		case "torrent-verify":
+			t.fields["status"] = statusCheck
+		}
+		t.changedAt = now
+	}
+	return
+}
+
+func (s *Server) torrentGet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		Fields []string        `json:"fields"`
+		IDs    json.RawMessage `json:"ids"`
+		Format string          `json:"format"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	if len(args.Fields) == 0 {
+		err = rpcError("no fields specified")
+		return
+	}
+	sel, err := s.selectTorrents(args.IDs)
+	if err != nil {
+		return
+	}
+	result := make(map[string]interface{}, 2)
+	switch args.Format {
+	case "", "objects":
+		torrents := make([]map[string]interface{}, 0, len(sel.ids))
+		for _, id := range sel.ids {
+			object := make(map[string]interface{}, len(args.Fields))
+			for _, field := range args.Fields {
+				if value, found := s.torrents[id].fields[field]; found {
+					object[field] = value
+				}
+			}
+			torrents = append(torrents, object)
+		}
+		result["torrents"] = torrents
+	case "table":
+		table := make([][]interface{}, 0, len(sel.ids)+1)
+		header := make([]interface{}, len(args.Fields))
+		for index, field := range args.Fields {
+			header[index] = field
+		}
+		table = append(table, header)
+		for _, id := range sel.ids {
+			row := make([]interface{}, len(args.Fields))
+			for index, field := range args.Fields {
+				row[index] = s.torrents[id].fields[field]
+			}
+			table = append(table, row)
+		}
+		result["torrents"] = table
+	default:
+		err = rpcError(fmt.Sprintf("invalid format '%s'", args.Format))
+		return
+	}
+	if sel.recentlyActive {
+		removed := make([]int64, 0)
+		threshold := time.Now().Add(-recentlyActiveWindow)
+		for _, r := range s.removed {
+			if r.removedAt.After(threshold) {
+				removed = append(removed, r.id)
+			}
+		}
+		result["removed"] = removed
+	}
+	return result, nil
+}
+
+func (s *Server) torrentSet(arguments json.RawMessage) (answer interface{}, err error) {
+	var args map[string]json.RawMessage
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	sel, err := s.selectTorrents(args["ids"])
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, id := range sel.ids {
+		t := s.torrents[id]
+		for key, raw := range args {
+			var value interface{}
+			if err = json.Unmarshal(raw, &value); err != nil {
+				return nil, rpcError(fmt.Sprintf("invalid '%s' value: %v", key, err))
+			}
+			switch key {
+			case "ids", "files-wanted", "files-unwanted", "priority-high", "priority-low", "priority-normal":
+				// not reflected in the fake state
+			case "location":
+				t.fields["downloadDir"] = value
+			case "trackerList":
+				list, _ := value.(string)
+				setTrackers(t, splitTrackerList(list))
+			case "trackerAdd":
+				urls, _ := value.([]interface{})
+				trackers := currentTrackers(t)
+				for _, announce := range urls {
+					if str, ok := announce.(string); ok {
+						trackers = append(trackers, []string{str})
+					}
+				}
+				setTrackers(t, trackers)
+			case "trackerRemove":
+				removeTrackers(t, value)
+			default:
+				t.fields[key] = value
+			}
+		}
+		t.changedAt = now
+	}
+	return
+}
+
+func (s *Server) torrentAdd(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		Filename    *string  `json:"filename"`
+		MetaInfo    *string  `json:"metainfo"`
+		DownloadDir *string  `json:"download-dir"`
+		Paused      *bool    `json:"paused"`
+		Labels      []string `json:"labels"`
+		PeerLimit   *int64   `json:"peer-limit"`
+		Priority    *int64   `json:"bandwidthPriority"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	var name, hash string
+	switch {
+	case args.Filename != nil && strings.HasPrefix(*args.Filename, "magnet:"):
+		magnet, parseErr := url.Parse(*args.Filename)
+		if parseErr != nil {
+			err = rpcError("invalid or corrupt torrent file")
+			return
+		}
+		hash = strings.ToLower(strings.TrimPrefix(magnet.Query().Get("xt"), "urn:btih:"))
+		name = magnet.Query().Get("dn")
+	case args.Filename != nil:
+		sum := sha1.Sum([]byte(*args.Filename))
+		hash = hex.EncodeToString(sum[:])
+		name = strings.TrimSuffix(path.Base(*args.Filename), ".torrent")
+	case args.MetaInfo != nil:
+		sum := sha1.Sum([]byte(*args.MetaInfo))
+		hash = hex.EncodeToString(sum[:])
+	default:
+		err = rpcError("no filename or metainfo specified")
+		return
+	}
+	// Duplicate ?
+	if id := s.findHash(hash); id != 0 {
+		t := s.torrents[id]
+		return map[string]interface{}{
+			"torrent-duplicate": addedTorrent(t),
+		}, nil
+	}
+	// Add
+	t := s.newTorrent(name, hash)
+	if args.DownloadDir != nil {
+		t.fields["downloadDir"] = *args.DownloadDir
+	}
+	if args.Labels != nil {
+		t.fields["labels"] = args.Labels
+	}
+	if args.PeerLimit != nil {
+		t.fields["peer-limit"] = *args.PeerLimit
+	}
+	if args.Priority != nil {
+		t.fields["bandwidthPriority"] = *args.Priority
+	}
+	startAdded, _ := s.session["start-added-torrents"].(bool)
+	if (args.Paused == nil && startAdded) || (args.Paused != nil && !*args.Paused) {
+		t.fields["status"] = statusDownload
+	}
+	return map[string]interface{}{
+		"torrent-added": addedTorrent(t),
+	}, nil
+}
+
+func addedTorrent(t *torrent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         t.fields["id"],
+		"name":       t.fields["name"],
+		"hashString": t.fields["hashString"],
+	}
+}
+
+func (s *Server) torrentRemove(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		IDs             json.RawMessage `json:"ids"`
+		DeleteLocalData bool            `json:"delete-local-data"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	sel, err := s.selectTorrents(args.IDs)
+	if err != nil {
+		return
+	}
+	for _, id := range sel.ids {
+		s.removeTorrent(id)
+	}
+	return
+}
+
+func (s *Server) torrentSetLocation(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		IDs      json.RawMessage `json:"ids"`
+		Location string          `json:"location"`
+		Move     bool            `json:"move"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	if args.Location == "" {
+		err = rpcError("no location")
+		return
+	}
+	sel, err := s.selectTorrents(args.IDs)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, id := range sel.ids {
+		s.torrents[id].fields["downloadDir"] = args.Location
+		s.torrents[id].changedAt = now
+	}
+	return
+}
+
+func (s *Server) torrentRenamePath(arguments json.RawMessage) (answer interface{}, err error) {
+	var args struct {
+		IDs  json.RawMessage `json:"ids"`
+		Path string          `json:"path"`
+		Name string          `json:"name"`
+	}
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	sel, err := s.selectTorrents(args.IDs)
+	if err != nil {
+		return
+	}
+	if len(sel.ids) != 1 {
+		err = rpcError("torrent-rename-path requires 1 torrent")
+		return
+	}
+	if args.Name == "" || strings.Contains(args.Name, "/") {
+		err = rpcError("Invalid argument")
+		return
+	}
+	t := s.torrents[sel.ids[0]]
+	if args.Path == t.fields["name"] {
+		t.fields["name"] = args.Name
+	}
+	t.changedAt = time.Now()
+	return map[string]interface{}{
+		"id":   t.fields["id"],
+		"path": args.Path,
+		"name": args.Name,
+	}, nil
+}
+
+func (s *Server) queueMove(method string, arguments json.RawMessage) (answer interface{}, err error) {
+	var args idsArguments
+	if err = decodeArguments(arguments, &args); err != nil {
+		return
+	}
+	sel, err := s.selectTorrents(args.IDs)
+	if err != nil {
+		return
+	}
+	// Current queue order
+	queue := s.sortedIDs()
+	sort.SliceStable(queue, func(i, j int) bool {
+		return toInt64(s.torrents[queue[i]].fields["queuePosition"]) < toInt64(s.torrents[queue[j]].fields["queuePosition"])
+	})
+	selected := make(map[int64]bool, len(sel.ids))
+	for _, id := range sel.ids {
+		selected[id] = true
+	}
+	switch method {
+	case "queue-move-top", "queue-move-bottom":
+		var moved, others []int64
+		for _, id := range queue {
+			if selected[id] {
+				moved = append(moved, id)
+			} else {
+				others = append(others, id)
+			}
+		}
+		if method == "queue-move-top" {
+			queue = append(moved, others...)
+		} else {
+			queue = append(others, moved...)
+		}
+	case "queue-move-up":
+		for index := 1; index < len(queue); index++ {
+			if selected[queue[index]] && !selected[queue[index-1]] {
+				queue[index], queue[index-1] = queue[index-1], queue[index]
+			}
+		}
+	case "queue-move-down":
+		for index := len(queue) - 2; index >= 0; index-- {
+			if selected[queue[index]] && !selected[queue[index+1]] {
+				queue[index], queue[index+1] = queue[index+1], queue[index]
+			}
+		}
+	}
+	now := time.Now()
+	for position, id := range queue {
+		if toInt64(s.torrents[id].fields["queuePosition"]) != int64(position) {
+			s.torrents[id].fields["queuePosition"] = position
+			s.torrents[id].changedAt = now
+		}
+	}
+	return
+}
+
+// normalizeQueue must be called with the mutex held.
+func (s *Server) normalizeQueue() {
+	queue := s.sortedIDs()
+	sort.SliceStable(queue, func(i, j int) bool {
+		return toInt64(s.torrents[queue[i]].fields["queuePosition"]) < toInt64(s.torrents[queue[j]].fields["queuePosition"])
+	})
+	for position, id := range queue {
+		s.torrents[id].fields["queuePosition"] = position
+	}
+}
+
+/*
+	Trackers
+*/
+
+// splitTrackerList converts a trackerList string into tiers of announce URLs.
+func splitTrackerList(list string) (tiers [][]string) {
+	var current []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(current) > 0 {
+				tiers = append(tiers, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		tiers = append(tiers, current)
+	}
+	return
+}
+
+func currentTrackers(t *torrent) (tiers [][]string) {
+	list, _ := t.fields["trackerList"].(string)
+	return splitTrackerList(list)
+}
+
+func setTrackers(t *torrent, tiers [][]string) {
+	var (
+		lines    []string
+		trackers []interface{}
+		id       int64
+	)
+	for tier, announces := range tiers {
+		if tier > 0 {
+			lines = append(lines, "")
+		}
+		for _, announce := range announces {
+			lines = append(lines, announce)
+			trackers = append(trackers, map[string]interface{}{
+				"announce": announce,
+				"id":       id,
+				"scrape":   strings.Replace(announce, "/announce", "/scrape", 1),
+				"sitename": siteName(announce),
+				"tier":     tier,
+			})
+			id++
+		}
+	}
+	if trackers == nil {
+		trackers = []interface{}{}
+	}
+	t.fields["trackerList"] = strings.Join(lines, "\n")
+	t.fields["trackers"] = trackers
+}
+
+func removeTrackers(t *torrent, value interface{}) {
+	ids, _ := value.([]interface{})
+	toRemove := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		toRemove[toInt64(id)] = true
+	}
+	var (
+		tiers [][]string
+		id    int64
+	)
+	for _, announces := range currentTrackers(t) {
+		var kept []string
+		for _, announce := range announces {
+			if !toRemove[id] {
+				kept = append(kept, announce)
+			}
+			id++
+		}
+		if len(kept) > 0 {
+			tiers = append(tiers, kept)
+		}
+	}
+	setTrackers(t, tiers)
+}
+
+func siteName(announce string) string {
+	parsed, err := url.Parse(announce)
+	if err != nil {
+		return ""
+	}
+	host := strings.Split(parsed.Hostname(), ".")
+	if len(host) >= 2 {
+		return host[len(host)-2]
+	}
+	return parsed.Hostname()
+}
+
+/*
+	Numbers helpers
+*/
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
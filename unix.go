@@ -0,0 +1,49 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+)
+
+/*
+	Unix socket
+	Transmission 4 can expose its RPC server on a unix socket (rpc-bind-address set to "unix:/path").
+*/
+
+const (
+	unixScheme     = "unix"
+	defaultRPCPath = "/transmission/rpc"
+	// unixSocketHost is the host used within the HTTP requests sent over a unix socket: it is
+	// part of the default daemon host whitelist.
+	unixSocketHost = "localhost"
+)
+
+// unixSocketEndpoint extracts the socket path of a unix scheme endpoint and returns the HTTP endpoint
+// to use over it.
+func unixSocketEndpoint(endpoint url.URL) (socket string, httpEndpoint url.URL, err error) {
+	if socket = endpoint.Path; socket == "" {
+		err = errors.New("unix socket endpoint must contain the socket path")
+		return
+	}
+	rpcPath := endpoint.Query().Get("path")
+	if rpcPath == "" {
+		rpcPath = defaultRPCPath
+	}
+	httpEndpoint = url.URL{
+		Scheme: "http",
+		User:   endpoint.User,
+		Host:   unixSocketHost,
+		Path:   rpcPath,
+	}
+	return
+}
+
+// unixSocketDialer returns a dial function connecting to the given unix socket whatever the address.
+func unixSocketDialer(socket string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+}
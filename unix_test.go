@@ -0,0 +1,94 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/hekmon/transmissionrpc/v3/transmissionrpctest"
+)
+
+// serveOnUnixSocket exposes daemon on a unix socket and returns its path along with the hosts
+// of the requests received on it.
+func serveOnUnixSocket(t *testing.T, daemon *transmissionrpctest.Server) (socket string, hosts func() []string) {
+	t.Helper()
+	// socket paths are limited in length: avoid the long test temp dirs
+	dir, err := os.MkdirTemp("", "trpc")
+	if err != nil {
+		t.Fatalf("can't create socket dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket = filepath.Join(dir, "rpc.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	var (
+		mutex    sync.Mutex
+		received []string
+	)
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: daemon.URL().Host})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		received = append(received, r.Host)
+		mutex.Unlock()
+		proxy.ServeHTTP(w, r)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { server.Close() })
+	hosts = func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), received...)
+	}
+	return
+}
+
+func TestUnixSocketEndpoint(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.AddTorrent(map[string]interface{}{"name": "over-socket"})
+	socket, hosts := serveOnUnixSocket(t, daemon)
+	ctx := context.Background()
+	// socket within the endpoint URL
+	client, err := transmissionrpc.New(&url.URL{Scheme: "unix", Path: socket}, nil)
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	torrents, err := client.TorrentGetAll(ctx)
+	if err != nil {
+		t.Fatalf("call over the unix socket failed: %v", err)
+	}
+	if len(torrents) != 1 || *torrents[0].Name != "over-socket" {
+		t.Fatalf("unexpected torrents: %+v", torrents)
+	}
+	// socket within the config
+	client, err = transmissionrpc.New(&url.URL{Scheme: "http", Host: "ignored", Path: transmissionrpctest.RPCPath},
+		&transmissionrpc.Config{UnixSocket: socket})
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	if _, err = client.SessionStats(ctx); err != nil {
+		t.Fatalf("call over the configured unix socket failed: %v", err)
+	}
+	received := hosts()
+	if len(received) == 0 || received[0] != "localhost" {
+		t.Fatalf("expected requests for the localhost whitelisted host, got %v", received)
+	}
+}
+
+func TestUnixSocketEndpointValidation(t *testing.T) {
+	if _, err := transmissionrpc.New(&url.URL{Scheme: "unix"}, nil); err == nil {
+		t.Fatal("expected a unix endpoint without socket path to be rejected")
+	}
+	if _, err := transmissionrpc.New(&url.URL{Scheme: "unix", Path: "/run/transmission.sock"},
+		&transmissionrpc.Config{CustomClient: http.DefaultClient}); err == nil {
+		t.Fatal("expected a unix socket with a custom HTTP client to be rejected")
+	}
+}
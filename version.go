@@ -0,0 +1,212 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/*
+	Server version discovery and feature gating
+	https://github.com/transmission/transmission/blob/4.0.3/docs/rpc-spec.md#5-protocol-versions
+*/
+
+// ServerVersion contains the versions advertised by the daemon.
+type ServerVersion struct {
+	RPCVersion        int64  // the current RPC API version
+	RPCVersionMinimum int64  // the minimum RPC API version supported
+	RPCVersionSemVer  string // the current RPC API version in a semver-compatible string (RPC v17)
+	Version           string // long version string "$version ($revision)"
+}
+
+// Supports returns true if the daemon RPC version is at least rpcVersion.
+func (sv ServerVersion) Supports(rpcVersion int64) bool {
+	return sv.RPCVersion >= rpcVersion
+}
+
+var serverVersionFields = []string{"rpc-version", "rpc-version-minimum", "rpc-version-semver", "version"}
+
+// ServerVersion returns the versions of the daemon. They are requested once and cached: the cache is
+// dropped when the daemon session id changes (daemon restart, maybe upgraded).
+func (c *Client) ServerVersion(ctx context.Context) (version ServerVersion, err error) {
+	if cached := c.serverVersion.Load(); cached != nil {
+		return *cached, nil
+	}
+	// Serialize the discovery to avoid concurrent first callers all requesting it
+	c.serverVersionDiscovery.Lock()
+	defer c.serverVersionDiscovery.Unlock()
+	if cached := c.serverVersion.Load(); cached != nil {
+		return *cached, nil
+	}
+	return c.discoverServerVersion(ctx)
+}
+
+// discoverServerVersion requests the daemon versions and caches them.
+// Caller must hold the serverVersionDiscovery lock.
+func (c *Client) discoverServerVersion(ctx context.Context) (version ServerVersion, err error) {
+	var payload SessionArguments
+	if err = c.rpcCall(ctx, "session-get", sessionGetParams{Fields: serverVersionFields}, &payload); err != nil {
+		err = fmt.Errorf("'session-get' rpc method failed: %w", err)
+		return
+	}
+	if payload.RPCVersion == nil {
+		err = errors.New("payload RPC Version is nil")
+		return
+	}
+	version.RPCVersion = *payload.RPCVersion
+	if payload.RPCVersionMinimum != nil {
+		version.RPCVersionMinimum = *payload.RPCVersionMinimum
+	}
+	if payload.RPCVersionSemVer != nil {
+		version.RPCVersionSemVer = *payload.RPCVersionSemVer
+	}
+	if payload.Version != nil {
+		version.Version = *payload.Version
+	}
+	c.serverVersion.Store(&version)
+	return
+}
+
+// requireRPCVersion returns an UnsupportedError if the daemon RPC version is lower than rpcVersion.
+func (c *Client) requireRPCVersion(ctx context.Context, feature string, rpcVersion int64) (err error) {
+	if c.noFeatureGating {
+		return
+	}
+	version, err := c.ServerVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("can't check if %s is supported by the server: %w", feature, err)
+	}
+	if !version.Supports(rpcVersion) {
+		err = &UnsupportedError{
+			Feature:         feature,
+			RequiredVersion: rpcVersion,
+			ServerVersion:   version.RPCVersion,
+		}
+	}
+	return
+}
+
+/*
+	Features
+*/
+
+const (
+	tableFormatRPCVersion        = 16
+	bandwidthGroupsRPCVersion    = 17
+	portTestIPProtocolRPCVersion = 18
+)
+
+// torrentFieldsRPCVersions contains the torrent-get fields not available on all the daemons.
+var torrentFieldsRPCVersions = map[string]int64{
+	"labels":              16,
+	"availability":        17,
+	"bytes_completed":     18,
+	"file-count":          17,
+	"group":               17,
+	"percentComplete":     17,
+	"primary-mime-type":   17,
+	"trackerList":         17,
+	"sequential_download": 18,
+}
+
+// torrentSetRPCVersions contains the torrent-set mutators not available on all the daemons.
+var torrentSetRPCVersions = map[string]int64{
+	"labels":              16,
+	"group":               17,
+	"trackerList":         17,
+	"sequential_download": 18,
+}
+
+// torrentAddRPCVersions contains the torrent-add arguments not available on all the daemons.
+var torrentAddRPCVersions = map[string]int64{
+	"labels":              17,
+	"sequential_download": 18,
+}
+
+// sessionFieldsRPCVersions contains the session fields not available on all the daemons.
+var sessionFieldsRPCVersions = map[string]int64{
+	"anti_brute_force_enabled":             18,
+	"anti_brute_force_threshold":           18,
+	"default-trackers":                     17,
+	"rpc-version-semver":                   17,
+	"script-torrent-added-enabled":         17,
+	"script-torrent-added-filename":        17,
+	"script-torrent-done-seeding-enabled":  17,
+	"script-torrent-done-seeding-filename": 17,
+}
+
+// checkSessionFields fails if one of the session fields is not supported by the daemon.
+func (c *Client) checkSessionFields(ctx context.Context, fields []string) (err error) {
+	for _, field := range fields {
+		if rpcVersion, found := sessionFieldsRPCVersions[field]; found {
+			if err = c.requireRPCVersion(ctx, fmt.Sprintf("session field '%s'", field), rpcVersion); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// checkTorrentAddArguments fails if one of the torrent-add arguments is not supported by the daemon.
+func (c *Client) checkTorrentAddArguments(ctx context.Context, arguments []string) (err error) {
+	for _, argument := range arguments {
+		if rpcVersion, found := torrentAddRPCVersions[argument]; found {
+			if err = c.requireRPCVersion(ctx, fmt.Sprintf("torrent-add argument '%s'", argument), rpcVersion); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// checkTorrentFields fails if one of the torrent-get fields is not supported by the daemon.
+func (c *Client) checkTorrentFields(ctx context.Context, fields []string) (err error) {
+	for _, field := range fields {
+		if rpcVersion, found := torrentFieldsRPCVersions[field]; found {
+			if err = c.requireRPCVersion(ctx, fmt.Sprintf("torrent field '%s'", field), rpcVersion); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// supportedTorrentFields returns the torrent-get fields supported by the daemon.
+func (c *Client) supportedTorrentFields(ctx context.Context, fields []string) (supported []string, err error) {
+	if c.noFeatureGating {
+		return fields, nil
+	}
+	version, err := c.ServerVersion(ctx)
+	if err != nil {
+		err = fmt.Errorf("can't check the torrent fields supported by the server: %w", err)
+		return
+	}
+	supported = make([]string, 0, len(fields))
+	for _, field := range fields {
+		rpcVersion, found := torrentFieldsRPCVersions[field]
+		_, emulable := emulatedTorrentFields[field]
+		if !found || version.Supports(rpcVersion) || (c.compat && emulable) {
+			supported = append(supported, field)
+		}
+	}
+	return
+}
+
+// checkTorrentSetArguments fails if one of the torrent-set mutators is not supported by the daemon.
+func (c *Client) checkTorrentSetArguments(ctx context.Context, arguments map[string]interface{}) (err error) {
+	// sorted for a deterministic error
+	keys := make([]string, 0, len(arguments))
+	for key := range arguments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if rpcVersion, found := torrentSetRPCVersions[key]; found {
+			if err = c.requireRPCVersion(ctx, fmt.Sprintf("torrent mutator '%s'", key), rpcVersion); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
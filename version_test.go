@@ -0,0 +1,91 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+func TestFeatureGating(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	id := daemon.AddTorrent(map[string]interface{}{"name": "gated"})
+	client := newTestClient(t, daemon, nil)
+	ctx := context.Background()
+	group := "slow"
+	filename := "https://example.com/debian.torrent"
+	for name, call := range map[string]func() error{
+		"method": func() error {
+			_, err := client.BandwidthGroupGet(ctx, nil)
+			return err
+		},
+		"torrent field": func() error {
+			_, err := client.TorrentGet(ctx, []string{"id", "group"}, nil)
+			return err
+		},
+		"torrent mutator": func() error {
+			return client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{IDs: []int64{id}, Group: &group})
+		},
+		"torrent-add argument": func() error {
+			_, err := client.TorrentAdd(ctx, transmissionrpc.TorrentAddPayload{Filename: &filename, Labels: []string{"linux"}})
+			return err
+		},
+	} {
+		err := call()
+		if !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+			t.Fatalf("%s: expected ErrUnsupportedByServer, got %v", name, err)
+		}
+		var unsupported *transmissionrpc.UnsupportedError
+		if !errors.As(err, &unsupported) || unsupported.RequiredVersion != 17 || unsupported.ServerVersion != 16 {
+			t.Fatalf("%s: unexpected unsupported error: %v", name, err)
+		}
+	}
+	for _, request := range daemon.Requests() {
+		if request.Method != "session-get" {
+			t.Fatalf("unsupported calls must not be sent, got %s", request.Method)
+		}
+	}
+	if count := countRequests(daemon, "session-get"); count != 1 {
+		t.Fatalf("expected the server version to be discovered once, got %d session-get", count)
+	}
+	// supported features go through
+	if _, err := client.TorrentGet(ctx, []string{"id", "labels"}, nil); err != nil {
+		t.Fatalf("supported field rejected: %v", err)
+	}
+}
+
+func TestFeatureGatingFollowsDaemonRestart(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	client := newTestClient(t, daemon, nil)
+	ctx := context.Background()
+	if _, err := client.BandwidthGroupGet(ctx, nil); !errors.Is(err, transmissionrpc.ErrUnsupportedByServer) {
+		t.Fatalf("expected ErrUnsupportedByServer, got %v", err)
+	}
+	// upgraded daemon: the new session id drops the cached version
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 17})
+	daemon.RotateSessionID()
+	if _, err := client.SessionStats(ctx); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if _, err := client.BandwidthGroupGet(ctx, nil); err != nil {
+		t.Fatalf("expected the upgraded daemon to support groups: %v", err)
+	}
+}
+
+func TestDisableFeatureGating(t *testing.T) {
+	daemon := newTestDaemon(t)
+	daemon.UpdateSession(map[string]interface{}{"rpc-version": 16})
+	client := newTestClient(t, daemon, &transmissionrpc.Config{DisableFeatureGating: true})
+	if _, err := client.BandwidthGroupGet(context.Background(), nil); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if count := countRequests(daemon, "session-get"); count != 0 {
+		t.Fatalf("expected no version discovery, got %d session-get", count)
+	}
+	if count := countRequests(daemon, "group-get"); count != 1 {
+		t.Fatalf("expected the call to be sent, got %d group-get", count)
+	}
+}
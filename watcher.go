@@ -0,0 +1,407 @@
+package transmissionrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+	Torrent Watcher
+	Periodically polls torrent-get and emits the differences between two snapshots as typed events.
+*/
+
+const (
+	defaultWatcherInterval = 5 * time.Second
+	// Transmission only reports torrents changed (and removed) within the last 60 seconds
+	// when using the "recently-active" ids mode.
+	recentlyActiveWindow = 60 * time.Second
+)
+
+// watcherRequiredFields are always requested by the watcher as they are needed to compute the events.
+var watcherRequiredFields = []string{
+	"id",
+	"hashString",
+	"name",
+	"status",
+	"error",
+	"errorString",
+	"labels",
+	"percentDone",
+}
+
+// WatcherConfig allows to customize a Watcher.
+type WatcherConfig struct {
+	// Interval is the delay between two polls. Defaults to 5 seconds.
+	// If greater or equal than one minute, the full torrent list will be requested at each poll
+	// as the "recently-active" mode of transmission only covers the last 60 seconds. It is also
+	// requested when the last successful poll is older than that (failed polls, slow handler, etc...)
+	// and when the daemon has been restarted since the previous poll.
+	Interval time.Duration
+	// Fields are additional torrent fields to request. They will be available on the
+	// Torrent values embedded within the events.
+	Fields []string
+	// EmitInitial makes the watcher emit a TorrentAdded event for each torrent found
+	// during the first poll. Otherwise the first poll is only used as the reference snapshot.
+	EmitInitial bool
+	// OnError is called for each failed poll. The watcher keeps running after a failed poll.
+	// If nil, errors are silently discarded.
+	OnError func(err error)
+}
+
+// Watcher polls a transmission daemon and emits events when torrents change.
+// It must be created with Client.NewWatcher().
+type Watcher struct {
+	client      *Client
+	interval    time.Duration
+	fields      []string // additional fields
+	emitInitial bool
+	onError     func(err error)
+	// state, only accessed by the running loop
+	snapshot map[string]Torrent // by hash: ids are renumbered when the daemon restarts
+	hashes   map[int64]string   // ids of the snapshot torrents, to resolve the removed ones
+	session  uint64             // daemon session of the snapshot ids, see Client.daemonRestarts
+	lastPoll time.Time          // start of the last successful poll
+}
+
+// NewWatcher returns a Watcher ready to be started with Run() or Events().
+func (c *Client) NewWatcher(config WatcherConfig) (w *Watcher, err error) {
+	if err = c.validateTorrentFields(config.Fields); err != nil {
+		return
+	}
+	if config.Interval < 0 {
+		err = errors.New("watcher interval can't be negative")
+		return
+	}
+	if config.Interval == 0 {
+		config.Interval = defaultWatcherInterval
+	}
+	w = &Watcher{
+		client:      c,
+		interval:    config.Interval,
+		fields:      config.Fields,
+		emitInitial: config.EmitInitial,
+		onError:     config.OnError,
+	}
+	return
+}
+
+// Run polls the daemon until ctx is cancelled, calling handler for each event (from the calling goroutine).
+// The returned error is the context error once it is done.
+func (w *Watcher) Run(ctx context.Context, handler func(event TorrentEvent)) (err error) {
+	if handler == nil {
+		return errors.New("watcher handler can't be nil")
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.poll(ctx, handler)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Events starts the watcher in its own goroutine and returns the channel on which events are sent.
+// The channel is closed once ctx is cancelled and the polling goroutine has stopped.
+func (w *Watcher) Events(ctx context.Context) <-chan TorrentEvent {
+	events := make(chan TorrentEvent)
+	go func() {
+		defer close(events)
+		_ = w.Run(ctx, func(event TorrentEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return events
+}
+
+func (w *Watcher) poll(ctx context.Context, handler func(event TorrentEvent)) {
+	var (
+		fields   []string
+		torrents []Torrent
+		removed  []int64
+		err      error
+	)
+	// Changes older than the recently active window would be missed (failed polls, slow handler,
+	// etc...): the whole list is needed to catch up. So it is when the daemon restarted as the
+	// snapshot ids do not match its ones anymore.
+	start := time.Now()
+	session := w.client.daemonRestarts.Load()
+	fullRefresh := w.snapshot == nil || w.interval >= recentlyActiveWindow ||
+		start.Sub(w.lastPoll) >= recentlyActiveWindow || session != w.session
+	// required fields not supported by the daemon (labels on old ones) are simply not watched
+	if fields, err = w.client.supportedTorrentFields(ctx, watcherRequiredFields); err == nil {
+		fields = mergeFields(fields, w.fields)
+		if !fullRefresh {
+			torrents, removed, err = w.client.torrentGetRecentlyActive(ctx, fields)
+			// restart detected while polling: the answer comes from the new daemon
+			if err == nil && w.client.daemonRestarts.Load() != w.session {
+				fullRefresh = true
+			}
+		}
+		if fullRefresh {
+			session = w.client.daemonRestarts.Load()
+			torrents, err = w.client.torrentGet(ctx, fields, nil)
+		}
+	}
+	if err != nil {
+		if ctx.Err() == nil && w.onError != nil {
+			w.onError(fmt.Errorf("watcher poll failed: %w", err))
+		}
+		return
+	}
+	w.lastPoll = start
+	w.session = session
+	// First poll: build the reference snapshot
+	if w.snapshot == nil {
+		w.snapshot = make(map[string]Torrent, len(torrents))
+		w.hashes = make(map[int64]string, len(torrents))
+		for _, torrent := range torrents {
+			if torrent.ID == nil || torrent.HashString == nil {
+				continue
+			}
+			w.track(torrent)
+			if w.emitInitial {
+				handler(TorrentAdded{Torrent: torrent})
+			}
+		}
+		return
+	}
+	// Removed torrents
+	var removedHashes []string
+	if fullRefresh {
+		// every known torrent not listed anymore has been removed
+		seen := make(map[string]struct{}, len(torrents))
+		for _, torrent := range torrents {
+			if torrent.HashString != nil {
+				seen[*torrent.HashString] = struct{}{}
+			}
+		}
+		for hash := range w.snapshot {
+			if _, found := seen[hash]; !found {
+				removedHashes = append(removedHashes, hash)
+			}
+		}
+	} else {
+		for _, id := range removed {
+			if hash, known := w.hashes[id]; known {
+				removedHashes = append(removedHashes, hash)
+			}
+		}
+	}
+	// Compute events
+	for _, torrent := range torrents {
+		if torrent.ID == nil || torrent.HashString == nil {
+			continue
+		}
+		previous, known := w.snapshot[*torrent.HashString]
+		w.track(torrent)
+		if !known {
+			handler(TorrentAdded{Torrent: torrent})
+			continue
+		}
+		for _, event := range diffTorrents(previous, torrent) {
+			handler(event)
+		}
+	}
+	for _, hash := range removedHashes {
+		previous := w.snapshot[hash]
+		delete(w.snapshot, hash)
+		if id := derefInt64(previous.ID); w.hashes[id] == hash {
+			delete(w.hashes, id)
+		}
+		handler(TorrentRemoved{ID: derefInt64(previous.ID), Torrent: previous})
+	}
+}
+
+// track stores torrent within the snapshot. torrent id and hash must not be nil.
+func (w *Watcher) track(torrent Torrent) {
+	hash := *torrent.HashString
+	if previous, known := w.snapshot[hash]; known && w.hashes[*previous.ID] == hash {
+		delete(w.hashes, *previous.ID)
+	}
+	w.snapshot[hash] = torrent
+	w.hashes[*torrent.ID] = hash
+}
+
+func diffTorrents(previous, current Torrent) (events []TorrentEvent) {
+	// Status
+	if previous.Status != nil && current.Status != nil && *previous.Status != *current.Status {
+		events = append(events, StatusChanged{
+			Torrent:   current,
+			OldStatus: *previous.Status,
+			NewStatus: *current.Status,
+		})
+	}
+	// Completion
+	if previous.PercentDone != nil && current.PercentDone != nil &&
+		*previous.PercentDone < 1 && *current.PercentDone >= 1 {
+		events = append(events, TorrentCompleted{Torrent: current})
+	}
+	// Error
+	var previousError, currentError int64
+	if previous.Error != nil {
+		previousError = *previous.Error
+	}
+	if current.Error != nil {
+		currentError = *current.Error
+	}
+	if currentError != 0 && (previousError != currentError || derefString(previous.ErrorString) != derefString(current.ErrorString)) {
+		events = append(events, ErrorRaised{
+			Torrent:     current,
+			Error:       currentError,
+			ErrorString: derefString(current.ErrorString),
+		})
+	} else if previousError != 0 && currentError == 0 {
+		events = append(events, ErrorCleared{Torrent: current})
+	}
+	// Labels
+	if !equalStrings(previous.Labels, current.Labels) {
+		events = append(events, LabelsChanged{
+			Torrent:   current,
+			OldLabels: previous.Labels,
+			NewLabels: current.Labels,
+		})
+	}
+	return
+}
+
+// TorrentEvent is implemented by all the events emitted by a Watcher:
+// TorrentAdded, TorrentRemoved, TorrentCompleted, StatusChanged, ErrorRaised, ErrorCleared and LabelsChanged.
+type TorrentEvent interface {
+	// TorrentID returns the id of the torrent concerned by the event
+	TorrentID() int64
+}
+
+// TorrentAdded is emitted when a new torrent is found.
+type TorrentAdded struct {
+	Torrent Torrent
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e TorrentAdded) TorrentID() int64 {
+	return derefInt64(e.Torrent.ID)
+}
+
+// TorrentRemoved is emitted when a torrent is not present anymore.
+// Torrent holds its last known values.
+type TorrentRemoved struct {
+	ID      int64
+	Torrent Torrent
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e TorrentRemoved) TorrentID() int64 {
+	return e.ID
+}
+
+// TorrentCompleted is emitted when a torrent finishes downloading its wanted files.
+type TorrentCompleted struct {
+	Torrent Torrent
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e TorrentCompleted) TorrentID() int64 {
+	return derefInt64(e.Torrent.ID)
+}
+
+// StatusChanged is emitted when the status of a torrent changes.
+type StatusChanged struct {
+	Torrent   Torrent
+	OldStatus TorrentStatus
+	NewStatus TorrentStatus
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e StatusChanged) TorrentID() int64 {
+	return derefInt64(e.Torrent.ID)
+}
+
+// ErrorRaised is emitted when a torrent enters an error state (or when its error changes).
+type ErrorRaised struct {
+	Torrent     Torrent
+	Error       int64
+	ErrorString string
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e ErrorRaised) TorrentID() int64 {
+	return derefInt64(e.Torrent.ID)
+}
+
+// ErrorCleared is emitted when a torrent previously in error is not anymore.
+type ErrorCleared struct {
+	Torrent Torrent
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e ErrorCleared) TorrentID() int64 {
+	return derefInt64(e.Torrent.ID)
+}
+
+// LabelsChanged is emitted when the labels of a torrent are modified.
+type LabelsChanged struct {
+	Torrent   Torrent
+	OldLabels []string
+	NewLabels []string
+}
+
+// TorrentID implements the TorrentEvent interface
+func (e LabelsChanged) TorrentID() int64 {
+	return derefInt64(e.Torrent.ID)
+}
+
+/*
+	Helpers
+*/
+
+func mergeFields(base, extra []string) (merged []string) {
+	merged = make([]string, len(base), len(base)+len(extra))
+	copy(merged, base)
+	var known bool
+	for _, field := range extra {
+		known = false
+		for _, existing := range merged {
+			if field == existing {
+				known = true
+				break
+			}
+		}
+		if !known {
+			merged = append(merged, field)
+		}
+	}
+	return
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt64(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
@@ -0,0 +1,345 @@
+package transmissionrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+)
+
+// watchedDaemon is a stub daemon whose torrents are modified by the tests. Like transmission, its
+// "recently-active" answers only contain the torrents changed and removed since the last poll.
+type watchedDaemon struct {
+	*stubDaemon
+	mutex    sync.Mutex
+	torrents map[int64]map[string]interface{}
+	changed  map[int64]bool
+	removed  []int64
+}
+
+func newWatchedDaemon(t *testing.T) *watchedDaemon {
+	t.Helper()
+	daemon := &watchedDaemon{
+		stubDaemon: newStubDaemon(t),
+		torrents:   make(map[int64]map[string]interface{}),
+		changed:    make(map[int64]bool),
+	}
+	daemon.handle("torrent-get", daemon.torrentGet)
+	return daemon
+}
+
+func (wd *watchedDaemon) torrentGet(arguments json.RawMessage) (interface{}, error) {
+	var args struct {
+		IDs json.RawMessage `json:"ids"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+	recentlyActive := string(args.IDs) == `"recently-active"`
+	torrents := make([]map[string]interface{}, 0, len(wd.torrents))
+	for _, id := range wd.sortedIDs() {
+		if !recentlyActive || wd.changed[id] {
+			torrents = append(torrents, wd.torrents[id])
+		}
+	}
+	result := map[string]interface{}{"torrents": torrents}
+	if recentlyActive {
+		result["removed"] = append([]int64{}, wd.removed...)
+	}
+	wd.changed = make(map[int64]bool)
+	wd.removed = nil
+	return result, nil
+}
+
+// sortedIDs must be called with the mutex held.
+func (wd *watchedDaemon) sortedIDs() (ids []int64) {
+	for id := range wd.torrents {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return
+}
+
+// set adds or updates the fields of the torrent with the given id.
+func (wd *watchedDaemon) set(id int64, fields map[string]interface{}) {
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+	torrent, found := wd.torrents[id]
+	if !found {
+		torrent = map[string]interface{}{
+			"id":          id,
+			"hashString":  fmt.Sprintf("%040x", id),
+			"name":        fmt.Sprintf("torrent %d", id),
+			"status":      int(transmissionrpc.TorrentStatusStopped),
+			"error":       0,
+			"errorString": "",
+			"labels":      []string{},
+			"percentDone": 0,
+		}
+		wd.torrents[id] = torrent
+	}
+	for key, value := range fields {
+		torrent[key] = value
+	}
+	wd.changed[id] = true
+}
+
+func (wd *watchedDaemon) remove(id int64) {
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+	delete(wd.torrents, id)
+	delete(wd.changed, id)
+	wd.removed = append(wd.removed, id)
+}
+
+// restart drops the torrents and changes the session id, as a daemon restart does.
+func (wd *watchedDaemon) restart() {
+	wd.mutex.Lock()
+	wd.torrents = make(map[int64]map[string]interface{})
+	wd.changed = make(map[int64]bool)
+	wd.removed = nil
+	wd.mutex.Unlock()
+	wd.rotateSessionID()
+}
+
+// describeEvent returns a short comparable description of event.
+func describeEvent(event transmissionrpc.TorrentEvent) string {
+	switch e := event.(type) {
+	case transmissionrpc.TorrentAdded:
+		return fmt.Sprintf("added %d", e.TorrentID())
+	case transmissionrpc.TorrentRemoved:
+		return fmt.Sprintf("removed %d (%s)", e.TorrentID(), *e.Torrent.Name)
+	case transmissionrpc.TorrentCompleted:
+		return fmt.Sprintf("completed %d", e.TorrentID())
+	case transmissionrpc.StatusChanged:
+		return fmt.Sprintf("status %d %s -> %s", e.TorrentID(), e.OldStatus, e.NewStatus)
+	case transmissionrpc.ErrorRaised:
+		return fmt.Sprintf("error %d %d %s", e.TorrentID(), e.Error, e.ErrorString)
+	case transmissionrpc.ErrorCleared:
+		return fmt.Sprintf("cleared %d", e.TorrentID())
+	case transmissionrpc.LabelsChanged:
+		return fmt.Sprintf("labels %d %v -> %v", e.TorrentID(), e.OldLabels, e.NewLabels)
+	default:
+		return fmt.Sprintf("unknown %T", event)
+	}
+}
+
+// pollEvents executes a single poll of watcher and returns the descriptions of the emitted events.
+func pollEvents(watcher *transmissionrpc.Watcher) (events []string) {
+	watcher.Poll(context.Background(), func(event transmissionrpc.TorrentEvent) {
+		events = append(events, describeEvent(event))
+	})
+	return
+}
+
+func TestWatcherEvents(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	daemon.set(1, map[string]interface{}{"percentDone": 0.5, "status": int(transmissionrpc.TorrentStatusDownload)})
+	daemon.set(2, nil)
+	daemon.set(3, nil)
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	if events := pollEvents(watcher); len(events) != 0 {
+		t.Fatalf("expected no event on the first poll, got %v", events)
+	}
+	for index, step := range []struct {
+		change   func()
+		expected []string
+	}{
+		{
+			change: func() {
+				daemon.set(1, map[string]interface{}{"percentDone": 1, "status": int(transmissionrpc.TorrentStatusSeed)})
+				daemon.set(4, nil)
+			},
+			expected: []string{"status 1 downloading -> seeding", "completed 1", "added 4"},
+		},
+		{
+			change: func() {
+				daemon.set(2, map[string]interface{}{"error": 2, "errorString": "tracker down"})
+				daemon.set(3, map[string]interface{}{"labels": []string{"linux"}})
+			},
+			expected: []string{"error 2 2 tracker down", "labels 3 [] -> [linux]"},
+		},
+		{
+			change: func() {
+				daemon.set(2, map[string]interface{}{"error": 0, "errorString": ""})
+				daemon.remove(3)
+			},
+			expected: []string{"cleared 2", "removed 3 (torrent 3)"},
+		},
+		{
+			change:   func() {},
+			expected: nil,
+		},
+	} {
+		step.change()
+		if events := pollEvents(watcher); !reflect.DeepEqual(events, step.expected) {
+			t.Fatalf("poll #%d: expected %v, got %v", index+2, step.expected, events)
+		}
+		if ids := daemon.lastArguments(t, "torrent-get"); !strings.Contains(ids, `"ids":"recently-active"`) {
+			t.Fatalf("poll #%d: expected a recently-active poll, got %s", index+2, ids)
+		}
+	}
+}
+
+func TestWatcherFullRefresh(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	daemon.set(1, nil)
+	daemon.set(2, nil)
+	// the recently active mode does not cover such an interval
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{Interval: time.Minute})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	pollEvents(watcher)
+	daemon.remove(1)
+	daemon.set(3, nil)
+	if events := pollEvents(watcher); !reflect.DeepEqual(events, []string{"added 3", "removed 1 (torrent 1)"}) {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); strings.Contains(arguments, `"ids"`) {
+		t.Fatalf("expected a full refresh, got %s", arguments)
+	}
+}
+
+func TestWatcherFullRefreshAfterMissedWindow(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	daemon.set(1, nil)
+	daemon.set(2, nil)
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{Interval: time.Second})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	pollEvents(watcher)
+	// the last successful poll is older than the recently active window
+	watcher.AgeLastPoll(2 * time.Minute)
+	daemon.remove(1)
+	if events := pollEvents(watcher); !reflect.DeepEqual(events, []string{"removed 1 (torrent 1)"}) {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); strings.Contains(arguments, `"ids"`) {
+		t.Fatalf("expected a full refresh, got %s", arguments)
+	}
+	// back within the window
+	pollEvents(watcher)
+	if arguments := daemon.lastArguments(t, "torrent-get"); !strings.Contains(arguments, `"ids":"recently-active"`) {
+		t.Fatalf("expected a recently-active poll, got %s", arguments)
+	}
+}
+
+func TestWatcherDaemonRestart(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	daemon.set(1, nil)
+	daemon.set(2, map[string]interface{}{"status": int(transmissionrpc.TorrentStatusDownload)})
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	pollEvents(watcher)
+	// the restarted daemon renumbered the torrents: the second one reuses the id of the first one
+	daemon.restart()
+	daemon.set(1, map[string]interface{}{
+		"hashString": fmt.Sprintf("%040x", 2),
+		"name":       "torrent 2",
+		"status":     int(transmissionrpc.TorrentStatusDownload),
+	})
+	daemon.set(2, map[string]interface{}{
+		"hashString": fmt.Sprintf("%040x", 3),
+		"name":       "torrent 3",
+	})
+	if events := pollEvents(watcher); !reflect.DeepEqual(events, []string{"added 2", "removed 1 (torrent 1)"}) {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); strings.Contains(arguments, `"ids"`) {
+		t.Fatalf("expected a full refresh, got %s", arguments)
+	}
+	// the new ids are used to resolve the removed torrents
+	daemon.remove(1)
+	if events := pollEvents(watcher); !reflect.DeepEqual(events, []string{"removed 1 (torrent 2)"}) {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if arguments := daemon.lastArguments(t, "torrent-get"); !strings.Contains(arguments, `"ids":"recently-active"`) {
+		t.Fatalf("expected a recently-active poll, got %s", arguments)
+	}
+}
+
+func TestWatcherEmitInitial(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	daemon.set(1, nil)
+	daemon.set(2, nil)
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{EmitInitial: true})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	if events := pollEvents(watcher); !reflect.DeepEqual(events, []string{"added 1", "added 2"}) {
+		t.Fatalf("unexpected initial events: %v", events)
+	}
+}
+
+func TestWatcherOnError(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	var failures []error
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{
+		OnError: func(err error) {
+			failures = append(failures, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	daemon.handle("torrent-get", func(json.RawMessage) (interface{}, error) {
+		return nil, errors.New("daemon busy")
+	})
+	pollEvents(watcher)
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "daemon busy") {
+		t.Fatalf("expected the failed poll to be reported, got %v", failures)
+	}
+}
+
+func TestWatcherEventsChannel(t *testing.T) {
+	daemon := newWatchedDaemon(t)
+	daemon.set(1, nil)
+	watcher, err := daemon.client(t, nil).NewWatcher(transmissionrpc.WatcherConfig{
+		Interval:    10 * time.Millisecond,
+		EmitInitial: true,
+	})
+	if err != nil {
+		t.Fatalf("can't create watcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watcher.Events(ctx)
+	select {
+	case event := <-events:
+		if description := describeEvent(event); description != "added 1" {
+			t.Fatalf("unexpected event: %s", description)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	// the channel is closed once the context is cancelled
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, open := <-events:
+			if !open {
+				return
+			}
+		case <-timeout:
+			t.Fatal("events channel not closed")
+		}
+	}
+}